@@ -7,6 +7,18 @@ const elements = {
   rangeValueQ: document.getElementById("range-value-q"),
   rangeValueT: document.getElementById("range-value-t"),
   gameType: document.getElementById("game-type"),
+  synchronized: document.getElementById("synchronized"),
+  teams: document.getElementById("teams"),
+  juryFinale: document.getElementById("jury-finale"),
+  flagVariant: document.getElementById("flag-variant"),
+  difficulty: document.getElementById("difficulty"),
+  region: document.getElementById("region"),
+  mapRetries: document.getElementById("map-retries"),
+  questionPack: document.getElementById("question-pack"),
+  packName: document.getElementById("pack-name"),
+  packCodes: document.getElementById("pack-codes"),
+  packOrdered: document.getElementById("pack-ordered"),
+  createPackBtn: document.getElementById("create-pack-btn"),
 };
 
 var gameMode = "MCQ";
@@ -37,6 +49,17 @@ const createRoom = async () => {
     const numQuestions = parseInt(elements.numQuestions.value, 10);
     const host = elements.hostUsername.value.trim();
     const gameType = gameMode;
+    const synchronized = elements.synchronized.checked;
+    // Trivia question types are mixed with regular flag questions
+    const trivia = Array.from(
+      document.querySelectorAll('input[name="question-type"]:checked'),
+    ).map((input) => input.value);
+    const questionTypes =
+      trivia.length > 0 && gameType !== "MAP" ? ["MCQ", ...trivia] : [];
+    const teams = elements.teams.value
+      .split(",")
+      .map((team) => team.trim())
+      .filter((team) => team);
 
     hideError();
 
@@ -53,6 +76,17 @@ const createRoom = async () => {
         timeLimit,
         numQuestions,
         gameType,
+        synchronized,
+        teams,
+        questionTypes,
+        juryFinale: elements.juryFinale.checked,
+        flagVariant: elements.flagVariant.value,
+        difficulty: elements.difficulty.value,
+        packId: elements.questionPack.value,
+        region: elements.region.value,
+        // Hot/cold hints after a wrong click, map rooms only
+        mapRetries:
+          gameType === "MAP" ? parseInt(elements.mapRetries.value, 10) : 0,
         hostUsername: host,
       }),
     });
@@ -63,12 +97,58 @@ const createRoom = async () => {
     }
 
     const data = await response.json();
+    // Logged in hosts play under their account name
+    localStorage.setItem("username", data.host);
     window.location.href = `/room?id=${data.code}`;
   } catch (error) {
     showError(error.message);
   }
 };
 
+const loadPacks = async (selected = "") => {
+  const response = await fetch("/api/packs");
+  if (!response.ok) return;
+
+  const packs = await response.json();
+  elements.questionPack.replaceChildren(elements.questionPack.options[0]);
+  packs.forEach((pack) => {
+    const option = document.createElement("option");
+    option.value = pack.id;
+    option.textContent = `${pack.name} (${pack.size} flags)`;
+    elements.questionPack.appendChild(option);
+  });
+  elements.questionPack.value = selected;
+};
+
+const createPack = async () => {
+  try {
+    hideError();
+
+    const codes = elements.packCodes.value
+      .split(/[\s,;]+/)
+      .filter((code) => code);
+    const response = await fetch("/api/packs", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        name: elements.packName.value.trim(),
+        [elements.packOrdered.checked ? "questions" : "codes"]: codes,
+      }),
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || "Unknown error.");
+    }
+
+    elements.packName.value = "";
+    elements.packCodes.value = "";
+    await loadPacks(data.id);
+  } catch (error) {
+    showError(error.message);
+  }
+};
+
 const storedUsername = localStorage.getItem("username");
 
 if (storedUsername) {
@@ -82,3 +162,5 @@ elements.timeLimit.addEventListener("input", (e) =>
   handleRangeInput(e, elements.rangeValueT),
 );
 elements.createRoomBtn.addEventListener("click", createRoom);
+elements.createPackBtn.addEventListener("click", createPack);
+loadPacks();
@@ -6,6 +6,7 @@ class GameLogic {
     this.currentQuestion = null;
     this.currentCallback = null;
     this.featuresLoaded = false;
+    this.revealTimer = null;
   }
 
   loadMapCSSAndJS(callback) {
@@ -20,16 +21,18 @@ class GameLogic {
     document.head.appendChild(jsScript);
   }
 
-  initializeMap(targetId) {
+  // Rooms only show the countries their questions are drawn from
+  initializeMap(targetId, roomID = "", bounds = null) {
     this.makeImageDraggable();
 
     this.vectorSource = new ol.source.Vector({
       format: new ol.format.GeoJSON(),
     });
 
-    fetch(
-      "https://raw.githubusercontent.com/adimail/fun-with-flags/refs/heads/master/frontend/static/countries.geo.json",
-    )
+    // The server locates clicks on outlines of the same tolerance
+    const query = new URLSearchParams({ tolerance: "0.05" });
+    if (roomID) query.set("room", roomID);
+    fetch(`/api/geo?${query}`)
       .then((response) => response.json())
       .then((data) => {
         const features = this.vectorSource.getFormat().readFeatures(data, {
@@ -90,6 +93,14 @@ class GameLogic {
       loadTilesWhileInteracting: true,
     });
 
+    // Regional games open on their play area, [west, south, east, north]
+    if (bounds) {
+      this.map.getView().fit(
+        ol.proj.transformExtent(bounds, "EPSG:4326", "EPSG:3857"),
+        { padding: [50, 50, 50, 50] },
+      );
+    }
+
     let disableHover = false;
 
     this.map.on("pointermove", (event) => {
@@ -256,9 +267,27 @@ class GameLogic {
     }, 2000);
   }
 
+  // Blurred flags (variant=blur) are sharpened one step every few
+  // seconds, other flags are shown as they are
+  showFlag(flagElement, url) {
+    clearInterval(this.revealTimer);
+    if (!url.includes("variant=blur")) {
+      flagElement.src = url;
+      return;
+    }
+
+    let step = 0;
+    flagElement.src = `${url}&step=${step}`;
+    this.revealTimer = setInterval(() => {
+      step++;
+      flagElement.src = `${url}&step=${step}`;
+      if (step >= 4) clearInterval(this.revealTimer);
+    }, 3000);
+  }
+
   loadMapQuestion(mapElement, flagElement, question, callback) {
     mapElement.classList.remove("hidden");
-    flagElement.src = question.flag_url;
+    this.showFlag(flagElement, question.flag_url);
     this.currentQuestion = question;
     this.currentCallback = callback;
   }
@@ -270,7 +299,7 @@ class GameLogic {
     callback,
     handleAnswer,
   ) {
-    flagElement.src = question.flag_url;
+    this.showFlag(flagElement, question.flag_url);
     const optionsArray = [...question.options];
     this.shuffleOptions(optionsArray);
 
@@ -284,6 +313,37 @@ class GameLogic {
     });
   }
 
+  // Reverse questions show a country name and four flags; options are
+  // flag URLs and the answer is the ISO code of the right flag
+  loadReverseQuestion(nameElement, optionsElement, question, callback) {
+    nameElement.textContent = question.country;
+    const optionsArray = [...question.options];
+    this.shuffleOptions(optionsArray);
+
+    optionsElement.innerHTML = optionsArray
+      .map(
+        (flagURL) =>
+          `<button class="option" data-code="${flagURL.split("/").pop().replace(".svg", "")}"><img src="${flagURL}" alt="Flag option" /></button>`,
+      )
+      .join("");
+
+    const buttons = Array.from(optionsElement.children);
+    buttons.forEach((button) => {
+      button.onclick = () => {
+        buttons.forEach((b) => (b.disabled = true));
+        const isCorrect = button.dataset.code === question.answer;
+        this.markAnswer(button, isCorrect);
+        if (!isCorrect) {
+          const correctButton = buttons.find(
+            (b) => b.dataset.code === question.answer,
+          );
+          if (correctButton) this.markAnswer(correctButton, true);
+        }
+        setTimeout(() => callback(isCorrect), 2000);
+      };
+    });
+  }
+
   highlightCountry(countryName, backgroundColor, borderColor) {
     const highlightSource = new ol.source.Vector();
     const highlightLayer = new ol.layer.Vector({
@@ -5,6 +5,9 @@ class MultiplayerGameController {
     this.elements = this.cacheElements();
     this.username = localStorage.getItem("username");
     this.roomID = new URLSearchParams(window.location.search).get("id");
+    // "/room?id={id}&spectate=1" watches the room without playing
+    this.spectator =
+      new URLSearchParams(window.location.search).get("spectate") === "1";
     this.socket = null;
     this.funwithflags = new GameLogic();
     this.gametype = null;
@@ -13,6 +16,8 @@ class MultiplayerGameController {
     this.gameended = false;
     this.ishost = false;
     this.gameTime = 0;
+    this.synchronized = false; // questions are pushed by the server
+    this.juryFinale = false; // scoreboard reveal after the last question
 
     this.gamePlayers = {}; // Structure: { playerId: { name, score } }
     this.currentQuestion = {}; // Structure: {type: "map/mcq", options = [], flag_url }
@@ -47,6 +52,7 @@ class MultiplayerGameController {
 
       // Game elements
       flag: document.getElementById("flag"),
+      prompt: document.getElementById("prompt"),
       options: document.getElementById("options"),
       progressMCQ: document.getElementById("progress-mcq"),
       progressMap: document.getElementById("progress-map"),
@@ -120,16 +126,21 @@ class MultiplayerGameController {
       this.populateRoomInfo(data);
       this.totalquestions = data.numQuestions;
       this.elements.playername.textContent = this.username;
-      this.gametype = data.gamemode;
+      // Elimination rounds are played as multiple choice questions
+      this.elimination = data.gamemode === "ELIMINATION";
+      this.gametype = this.elimination ? "MCQ" : data.gamemode;
       this.gameTime = data.timeLimit;
+      this.synchronized = data.synchronized;
+      this.juryFinale = data.juryFinale;
+      this.bounds = data.bbox;
 
       data.players.forEach((player) => {
-        this.addPlayer(player.id, player.username);
+        this.addPlayer(player.id, player.username, player.team);
       });
 
       this.updatePlayerCount();
 
-      const isHost = this.username === data.host;
+      const isHost = !this.spectator && this.username === data.host;
       const gameStartContainer = document.querySelector(".game-start");
       const button = gameStartContainer.querySelector("button");
       const message = gameStartContainer.querySelector("p");
@@ -151,7 +162,10 @@ class MultiplayerGameController {
   };
 
   initializeRoom() {
-    if (!this.username) {
+    if (this.spectator) {
+      this.username = this.username || "Spectator";
+      this.fetchRoomDetails();
+    } else if (!this.username) {
       this.askForUsername();
     } else {
       this.fetchRoomDetails();
@@ -169,6 +183,11 @@ class MultiplayerGameController {
       return;
     }
 
+    if (this.elimination && Object.keys(this.gamePlayers).length < 2) {
+      alert("Elimination games need at least 2 players.");
+      return;
+    }
+
     this.hidewaitingroom();
 
     this.gamestarted = true;
@@ -176,11 +195,18 @@ class MultiplayerGameController {
     this.socket.send(JSON.stringify({ event: "loadgame" }));
   }
 
+  // Spectators only follow the leaderboard, they never get questions
+  spectate() {
+    this.gamestarted = true;
+    this.hidewaitingroom();
+    this.elements.sidebar.classList.add("active");
+  }
+
   startGame() {
     try {
       if (this.gametype === "MAP") {
         this.funwithflags.loadMapCSSAndJS(() => {
-          this.funwithflags.initializeMap("map");
+          this.funwithflags.initializeMap("map", this.roomID, this.bounds);
 
           const map = this.funwithflags.map;
 
@@ -191,9 +217,10 @@ class MultiplayerGameController {
             );
 
             if (clickedFeature) {
-              const userSelectedCountry = clickedFeature.get("name");
+              // The server finds the country at the clicked point itself
+              const [lon, lat] = ol.proj.toLonLat(event.coordinate);
 
-              this.handleMapClick(userSelectedCountry);
+              this.handleMapClick(lon, lat);
             } else {
               alert("Please select a valid country.");
             }
@@ -209,8 +236,19 @@ class MultiplayerGameController {
     }
   }
 
-  handleMapClick(selectedCountry) {
-    this.requestAnswer(this.currentQuestionIndex, selectedCountry);
+  handleMapClick(lon, lat) {
+    if (this.gameended) return;
+
+    this.socket.send(
+      JSON.stringify({
+        event: "validate_answer",
+        data: {
+          question_index: this.currentQuestionIndex,
+          lon,
+          lat,
+        },
+      }),
+    );
   }
 
   shuffleOptions(array) {
@@ -226,7 +264,12 @@ class MultiplayerGameController {
       this.toggleVisibility(this.elements.gameMCQ, true);
       this.toggleVisibility(this.elements.gameMap, false);
 
-      this.elements.flag.src = this.currentQuestion.flag_url;
+      this.funwithflags.showFlag(
+        this.elements.flag,
+        this.currentQuestion.flag_url,
+      );
+      this.elements.prompt.textContent = this.currentQuestion.prompt || "";
+      this.toggleVisibility(this.elements.prompt, !!this.currentQuestion.prompt);
       const optionsArray = [...this.currentQuestion.options];
       this.shuffleOptions(optionsArray);
 
@@ -244,7 +287,10 @@ class MultiplayerGameController {
       this.toggleVisibility(this.elements.gameMCQ, false);
       this.toggleVisibility(this.elements.gameMap, true);
 
-      this.elements.flagMap.src = this.currentQuestion.flag_url;
+      this.funwithflags.showFlag(
+        this.elements.flagMap,
+        this.currentQuestion.flag_url,
+      );
     }
   }
 
@@ -345,6 +391,7 @@ class MultiplayerGameController {
   }
 
   moveToNextQuestion() {
+    if (this.synchronized) return;
     if (this.currentQuestionIndex < this.totalquestions - 1) {
       this.currentQuestionIndex += 1;
       this.requestQuestion(this.currentQuestionIndex);
@@ -358,7 +405,9 @@ class MultiplayerGameController {
     this.elements.playerList.innerHTML = "";
     players.forEach((player) => {
       const li = document.createElement("li");
-      li.textContent = `${player.username}`;
+      li.textContent = player.team
+        ? `${player.username} (${player.team})`
+        : `${player.username}`;
       li.setAttribute("data-id", player.id);
       this.elements.playerList.appendChild(li);
     });
@@ -377,6 +426,7 @@ class MultiplayerGameController {
       id,
       username: player.name,
       score: player.score,
+      team: player.team,
     }));
     this.populatePlayerList(players);
   }
@@ -429,9 +479,16 @@ class MultiplayerGameController {
   //
   // Handle websocket events related to players and game state
   //
-  addPlayer(playerId, playerName) {
+  addPlayer(playerId, playerName, team) {
     if (!this.gamePlayers[playerId]) {
-      this.gamePlayers[playerId] = { name: playerName, score: 0 };
+      this.gamePlayers[playerId] = { name: playerName, score: 0, team };
+    }
+    this.syncUI();
+  }
+
+  updateTeam(playerId, team) {
+    if (this.gamePlayers[playerId]) {
+      this.gamePlayers[playerId].team = team;
     }
     this.syncUI();
   }
@@ -453,8 +510,20 @@ class MultiplayerGameController {
   }
 
   updateCurrentQuestion(data) {
+    if (typeof data.question_index === "number") {
+      this.currentQuestionIndex = data.question_index;
+      this.hideError();
+      this.funwithflags.updateProgress(
+        this.gametype === "MCQ"
+          ? this.elements.progressMCQ
+          : this.elements.progressMap,
+        this.currentQuestionIndex,
+        this.totalquestions,
+      );
+    }
     this.currentQuestion.type = this.gametype;
     this.currentQuestion.options = data.options;
+    this.currentQuestion.prompt = data.prompt;
     this.currentQuestion.flag_url = data.flag_url;
   }
 
@@ -505,6 +574,51 @@ class MultiplayerGameController {
       .join("");
   }
 
+  lifeLost(data) {
+    if (data.username === this.username) {
+      this.showError(`You lost a life (${data.reason}), ${data.lives} left.`);
+    }
+  }
+
+  eliminated(data) {
+    if (data.username === this.username) {
+      this.gameended = true;
+      this.spectate();
+      alert("You have been eliminated, you can keep watching the game.");
+    }
+    this.removePlayer(data.id, data.username);
+  }
+
+  winner(data) {
+    alert(`${data.username} is the last player standing!`);
+  }
+
+  // Rooms with map retries send a hint instead of the answer after a wrong click
+  mapHint(data) {
+    if (this.gameended || data.question_index !== this.currentQuestionIndex)
+      return;
+
+    this.funwithflags.highlightCountry(
+      data.chosen_answer,
+      "rgba(255, 0, 0, 0.6)",
+      "#FF0000",
+    );
+    const tries = data.retries_left === 1 ? "try" : "tries";
+    this.showError(
+      `Not ${data.chosen_answer}, you're ${data.feedback}: ${data.distance_km} km ${data.direction}. ${data.retries_left} ${tries} left.`,
+    );
+  }
+
+  roundSummary(data) {
+    const correct = data.results.filter((result) => result.correct).length;
+    const fastest = data.fastest
+      ? ` Fastest: ${data.fastest.username} (${(data.fastest.time_ms / 1000).toFixed(1)}s).`
+      : "";
+    this.showError(
+      `${data.correct_answer}: ${correct}/${data.results.length} correct.${fastest}`,
+    );
+  }
+
   timeover() {
     this.gameended = true;
     alert("Game over");
@@ -545,6 +659,35 @@ class MultiplayerGameController {
     }
   }
 
+  // Eurovision scoreboard reveal: the leaderboard shows
+  // jury points instead of the score until the final ranking
+  juryPoints(data) {
+    if (!this.elements.sidebar.classList.contains("active")) {
+      this.toggleSidebar();
+    }
+    this.showError(
+      `${data.jury}: ` +
+        data.votes
+          .map((vote) => `${vote.points} points to ${vote.username}`)
+          .join(", "),
+    );
+    this.renderLeaderboard(
+      Object.entries(data.totals)
+        .map(([name, score]) => ({ name, score }))
+        .sort((a, b) => b.score - a.score),
+    );
+  }
+
+  scoreboardFinal(data) {
+    this.renderLeaderboard(
+      data.ranking.map((player) => ({
+        name: player.username,
+        score: player.points,
+      })),
+    );
+    this.endgame();
+  }
+
   endgame() {
     this.gameended = true;
     this.toggleSidebar();
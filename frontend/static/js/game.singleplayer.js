@@ -6,13 +6,21 @@ class SinglePlayerGameController {
     this.funwithflags = new GameLogic();
     this.initEventListeners();
     this.gameMode = "MCQ";
+    this.bounds = null; // map area of the region played, see fetchQuestions
   }
 
   cacheElements() {
     return {
       rangeValue: document.getElementById("range-value"),
       numQuestions: document.getElementById("num-questions"),
+      flagVariant: document.getElementById("flag-variant"),
+      difficulty: document.getElementById("difficulty"),
+      region: document.getElementById("region"),
       flag: document.getElementById("flag"),
+      countryName: document.getElementById("country-name"),
+      typedAnswer: document.getElementById("typed-answer"),
+      typedInput: document.getElementById("typed-input"),
+      typedResult: document.getElementById("typed-result"),
       options: document.getElementById("options"),
       progressMCQ: document.getElementById("progress-mcq"),
       progressMap: document.getElementById("progress-map"),
@@ -45,7 +53,9 @@ class SinglePlayerGameController {
 
   async startGame() {
     const numQuestions = parseInt(this.elements.numQuestions.value);
-    const gameType = this.gameMode;
+    // Practice plays multiple choice questions picked by the scheduler
+    this.practice = this.gameMode === "PRACTICE";
+    const gameType = this.practice ? "MCQ" : this.gameMode;
 
     if (isNaN(numQuestions) || numQuestions <= 0) {
       this.showError("Please enter a valid number of questions.");
@@ -56,19 +66,30 @@ class SinglePlayerGameController {
       this.toggleVisibility(this.elements.questionModal, false);
       this.toggleVisibility(this.elements.game, false);
 
-      const questions = await this.fetchQuestions(numQuestions, gameType);
+      const questions = this.practice
+        ? await this.fetchPracticeQuestions(numQuestions)
+        : await this.fetchQuestions(numQuestions, gameType);
+
+      if (questions.length === 0) {
+        this.toggleVisibility(this.elements.questionModal, true);
+        this.showError("No flags are due for practice, come back later!");
+        return;
+      }
 
       if (gameType === "MAP") {
         this.funwithflags.loadMapCSSAndJS(() => {
-          this.funwithflags.initializeMap("map");
+          this.funwithflags.initializeMap("map", "", this.bounds);
         });
       }
 
       this.toggleVisibility(this.elements.game, true);
 
       this.runGame(questions, gameType);
-    } catch {
-      this.showError("An error occurred while fetching the game data.");
+    } catch (error) {
+      this.toggleVisibility(this.elements.questionModal, true);
+      this.showError(
+        error.message || "An error occurred while fetching the game data.",
+      );
     }
   }
 
@@ -78,18 +99,41 @@ class SinglePlayerGameController {
       headers: {
         "X-Num-Questions": numQuestions.toString(),
         "game-type": gameType,
+        "X-Flag-Variant": this.elements.flagVariant.value,
+        "X-Difficulty": this.elements.difficulty.value,
+        "X-Region": this.elements.region.value,
       },
     });
     if (!response.ok) throw new Error("Failed to fetch questions.");
+    // The map zooms to the region played, "west,south,east,north"
+    const bbox = response.headers.get("X-Region-Bbox");
+    this.bounds = bbox ? bbox.split(",").map(Number) : null;
     return response.json();
   }
 
+  // Practice progress is kept on the server with the account
+  async fetchPracticeQuestions(count) {
+    const params = new URLSearchParams({
+      count: Math.min(count, 25).toString(),
+    });
+    const response = await fetch(`/api/practice/next?${params}`);
+    if (response.status === 401) {
+      throw new Error("Log in from the Account page to practice.");
+    }
+    if (!response.ok) throw new Error("Failed to fetch questions.");
+    const data = await response.json();
+    return data.questions;
+  }
+
   runGame(questions, gameType) {
     let currentIndex = 0;
     let score = 0;
+    let askedAt = Date.now();
 
     const nextQuestion = (correct) => {
       if (correct) score++;
+      this.reportAnswer(questions[currentIndex], correct, Date.now() - askedAt);
+      askedAt = Date.now();
       if (++currentIndex < questions.length) {
         this.loadQuestion(
           questions[currentIndex],
@@ -112,8 +156,74 @@ class SinglePlayerGameController {
     );
   }
 
+  // Answers are checked in the browser, the server keeps statistics
+  // of them to rate how hard each flag is, and schedules the next
+  // review of practiced flags. Both need a logged in player, guests
+  // get a 401 that is ignored
+  reportAnswer(question, correct, elapsed) {
+    const answer = {
+      type: question.type,
+      code: question.code,
+      correct: !!correct,
+      elapsed_ms: elapsed,
+    };
+    const url = this.practice ? "/api/practice/answer" : "/api/stats/answer";
+    fetch(url, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(answer),
+    }).catch((error) => console.error("Failed to report answer:", error));
+  }
+
   loadQuestion(question, currentIndex, totalQuestions, callback, gameType) {
-    if (gameType === "MCQ") {
+    if (gameType === "TYPED") {
+      this.toggleVisibility(this.elements.gameMCQ, true);
+      this.toggleVisibility(this.elements.gameMap, false);
+      this.toggleVisibility(this.elements.options, false);
+      this.toggleVisibility(this.elements.typedAnswer, true);
+
+      this.funwithflags.updateProgress(
+        this.elements.progressMCQ,
+        currentIndex,
+        totalQuestions,
+      );
+
+      this.funwithflags.showFlag(this.elements.flag, question.flag_url);
+      this.elements.typedInput.value = "";
+      this.elements.typedResult.textContent = "";
+      this.elements.typedAnswer.onsubmit = async (event) => {
+        event.preventDefault();
+        this.elements.typedAnswer.onsubmit = (e) => e.preventDefault();
+        const result = await this.checkTypedAnswer(
+          question.code,
+          this.elements.typedInput.value,
+        );
+        this.elements.typedResult.textContent = result.correct
+          ? result.near_miss
+            ? `Close enough: ${result.correct_answer}`
+            : "Correct!"
+          : `Wrong, it was ${result.correct_answer}`;
+        setTimeout(() => callback(result.correct), 2000);
+      };
+    } else if (gameType === "REVERSE") {
+      this.toggleVisibility(this.elements.gameMCQ, true);
+      this.toggleVisibility(this.elements.gameMap, false);
+      this.toggleVisibility(this.elements.flag, false);
+      this.toggleVisibility(this.elements.countryName, true);
+
+      this.funwithflags.updateProgress(
+        this.elements.progressMCQ,
+        currentIndex,
+        totalQuestions,
+      );
+
+      this.funwithflags.loadReverseQuestion(
+        this.elements.countryName,
+        this.elements.options,
+        question,
+        callback,
+      );
+    } else if (gameType === "MCQ") {
       this.toggleVisibility(this.elements.gameMCQ, true);
       this.toggleVisibility(this.elements.gameMap, false);
 
@@ -149,6 +259,16 @@ class SinglePlayerGameController {
     }
   }
 
+  async checkTypedAnswer(code, answer) {
+    const response = await fetch("/api/singleplayer/answer", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ code, answer }),
+    });
+    if (!response.ok) throw new Error("Failed to check answer.");
+    return response.json();
+  }
+
   toggleVisibility(element, visible) {
     element.classList.toggle("hidden", !visible);
   }
@@ -12,21 +12,48 @@ class WebSocketFunWithFlags {
     const message = JSON.parse(event.data);
     switch (message.event) {
       case "playerJoined":
-        this.controller.addPlayer(message.data.id, message.data.username);
+        this.controller.addPlayer(
+          message.data.id,
+          message.data.username,
+          message.data.team,
+        );
         this.controller.updatePlayerCount();
         break;
       case "playerLeft":
         this.controller.removePlayer(message.data.id, message.data.username);
         this.controller.updatePlayerCount();
         break;
+      case "team_changed":
+        this.controller.updateTeam(message.data.id, message.data.team);
+        break;
       case "countdown":
         this.controller.hidewaitingroom();
         this.renderCountdown(message.data);
         break;
+      case "spectating":
+        // Sent only to spectators on join, with a snapshot
+        // of the room so late joiners see the current scores
+        message.data.players.forEach((player) => {
+          this.controller.addPlayer(player.id, player.username, player.team);
+          this.controller.updateScore(player.id, player.score);
+        });
+        if (message.data.start) this.controller.spectate();
+        break;
       case "gameStarted":
         console.log("Game started");
+        if (this.controller.spectator) {
+          this.controller.spectate();
+          break;
+        }
         this.controller.startGame();
-        this.controller.requestQuestion(0);
+        if (!this.controller.synchronized) {
+          this.controller.requestQuestion(0);
+        }
+        break;
+      case "round_summary":
+        // Synchronized rooms only: results of the round
+        // that just closed, before the next question is pushed
+        this.controller.roundSummary(message.data);
         break;
       case "new_question":
         // When the client requests the question from the backend
@@ -40,15 +67,52 @@ class WebSocketFunWithFlags {
         // returns the choosen answer and correct answer
         this.controller.verifyAnswer(message.data);
         break;
+      case "answer_hint":
+        // Wrong map click in a room with retries: how far
+        // and which way the country is, and the tries left
+        this.controller.mapHint(message.data);
+        break;
       case "score":
         // When a user answers correctly, the backend
         // broadcasts score event to the entire room
         // so that all clients leaderboards gets updated
         this.controller.scoreUpdate(message.data);
         break;
+      case "life_lost":
+        this.controller.lifeLost(message.data);
+        break;
+      case "player_eliminated":
+        this.controller.eliminated(message.data);
+        break;
+      case "winner":
+        this.controller.winner(message.data);
+        break;
       case "finished_game":
         this.controller.finishGame(message.username);
         break;
+      case "game_result":
+        // Guests keep the claim code so the result can be added to
+        // an account they log in to later
+        if (message.data.claim_code) {
+          const codes = JSON.parse(localStorage.getItem("claimCodes") || "[]");
+          codes.push(message.data.claim_code);
+          localStorage.setItem("claimCodes", JSON.stringify(codes));
+        }
+        break;
+      case "badge_awarded":
+        this.controller.showError(
+          `${message.data.username} earned ${message.data.badge.icon} ${message.data.badge.name}`,
+        );
+        break;
+      case "announcement":
+        // Sent by the server admins to every open socket
+        this.controller.showError(`📢 ${message.data.message}`);
+        break;
+      case "room_closed":
+        // An admin removed the room, the socket is closed next
+        this.controller.gameended = true;
+        this.controller.showErrorModal(message.data.reason);
+        break;
       case "time_over":
         // When the game has ended, time over event is
         // send from the server and then it alerts the user that the game has ended.
@@ -56,8 +120,21 @@ class WebSocketFunWithFlags {
         break;
       case "all_players_finished":
         // This is the end point and this is when the game finished
-        // and the websocket connections are erased after this point
-        this.controller.endgame();
+        // and the websocket connections are erased after this point,
+        // unless the room reveals a Eurovision scoreboard first
+        if (!this.controller.juryFinale) {
+          this.controller.endgame();
+        }
+        break;
+      case "jury_start":
+        this.controller.showError(`Now voting: ${message.data.jury}`);
+        break;
+      case "jury_points":
+      case "douze_points":
+        this.controller.juryPoints(message.data);
+        break;
+      case "scoreboard_final":
+        this.controller.scoreboardFinal(message.data);
         break;
       default:
         console.warn("Unhandled WebSocket event:", message.event);
@@ -102,6 +179,7 @@ class WebSocketFunWithFlags {
           event: "joinRoom",
           username: this.username,
           roomID: this.roomID,
+          spectator: this.controller.spectator,
         }),
       );
     };
@@ -40,6 +40,9 @@ const joinRoom = async () => {
       return;
     }
 
+    // Logged in players play under their account name
+    const data = await response.json();
+    localStorage.setItem("username", data.username);
     window.location.href = `/room?id=${roomID}`;
   } catch (error) {
     showError(error.message);
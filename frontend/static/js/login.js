@@ -0,0 +1,170 @@
+const elements = {
+  loginModal: document.getElementById("login-modal"),
+  accountModal: document.getElementById("account-modal"),
+  accountUsername: document.getElementById("account-username"),
+  username: document.getElementById("username"),
+  password: document.getElementById("password"),
+  loginBtn: document.getElementById("login-btn"),
+  registerBtn: document.getElementById("register-btn"),
+  logoutBtn: document.getElementById("logout-btn"),
+  profileAvatar: document.getElementById("profile-avatar"),
+  profileDisplayName: document.getElementById("profile-display-name"),
+  profileStats: document.getElementById("profile-stats"),
+  displayName: document.getElementById("display-name"),
+  avatar: document.getElementById("avatar"),
+  country: document.getElementById("country"),
+  saveProfileBtn: document.getElementById("save-profile-btn"),
+  profileMessage: document.getElementById("profile-message"),
+  errorMessage: document.getElementById("error-message"),
+};
+
+const showError = (message) => {
+  elements.errorMessage.textContent = message;
+  elements.errorMessage.classList.remove("hidden");
+};
+
+const hideError = () => {
+  elements.errorMessage.textContent = "";
+  elements.errorMessage.classList.add("hidden");
+};
+
+const showAccount = async (username) => {
+  // Rooms are joined under the account name from now on
+  localStorage.setItem("username", username);
+  elements.accountUsername.textContent = username;
+  elements.loginModal.classList.add("hidden");
+  elements.accountModal.classList.remove("hidden");
+
+  await claimGuestResults();
+  const response = await fetch("/api/profile");
+  if (response.ok) {
+    renderProfile(await response.json());
+  }
+};
+
+// Results of games played as a guest in this browser
+const claimGuestResults = async () => {
+  const codes = JSON.parse(localStorage.getItem("claimCodes") || "[]");
+  if (codes.length === 0) return;
+
+  const response = await fetch("/api/profile/claim", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ codes }),
+  });
+  if (response.ok) {
+    localStorage.removeItem("claimCodes");
+  }
+};
+
+const renderProfile = (profile) => {
+  elements.profileAvatar.textContent = profile.avatar;
+  elements.profileDisplayName.textContent = profile.display_name;
+  elements.displayName.value = profile.display_name;
+  elements.avatar.value = profile.avatar;
+  elements.country.value = profile.country;
+
+  const rows = [
+    ["Country", profile.country || "-"],
+    ["Games played", profile.games_played],
+    ["Accuracy", `${profile.accuracy.toFixed(0)}% of ${profile.answers}`],
+    ...Object.entries(profile.modes).map(([mode, stats]) => [
+      `Accuracy (${mode})`,
+      `${stats.accuracy.toFixed(0)}% of ${stats.answers}`,
+    ]),
+    ["Best streak", profile.best_streak],
+    ["Days in a row", profile.day_streak],
+    [
+      "Badges",
+      profile.badges.map((badge) => `${badge.icon} ${badge.name}`).join(", ") ||
+        "-",
+    ],
+    ["Favourite flags", profile.favourites.join(", ") || "-"],
+    ["Most missed flags", profile.most_missed.join(", ") || "-"],
+  ];
+  elements.profileStats.replaceChildren(
+    ...rows.map(([label, value]) => {
+      const row = document.createElement("tr");
+      [label, value].forEach((text) => {
+        const cell = document.createElement("td");
+        cell.textContent = text;
+        row.appendChild(cell);
+      });
+      return row;
+    }),
+  );
+};
+
+const saveProfile = async () => {
+  elements.profileMessage.classList.add("hidden");
+
+  const response = await fetch("/api/profile", {
+    method: "PUT",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({
+      display_name: elements.displayName.value.trim(),
+      avatar: elements.avatar.value.trim(),
+      country: elements.country.value.trim(),
+    }),
+  });
+
+  const data = await response.json();
+  if (!response.ok) {
+    elements.profileMessage.textContent = data.error || "Unknown error.";
+    elements.profileMessage.classList.remove("hidden");
+    return;
+  }
+  renderProfile(data);
+};
+
+const showLogin = () => {
+  elements.accountModal.classList.add("hidden");
+  elements.loginModal.classList.remove("hidden");
+};
+
+// action is "login" or "register"
+const submit = async (action) => {
+  try {
+    hideError();
+
+    const response = await fetch(`/api/auth/${action}`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        username: elements.username.value.trim(),
+        password: elements.password.value,
+      }),
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+      showError(data.error || "Unknown error.");
+      return;
+    }
+
+    elements.password.value = "";
+    showAccount(data.username);
+  } catch (error) {
+    showError(error.message);
+  }
+};
+
+const logout = async () => {
+  await fetch("/api/auth/logout", { method: "POST" });
+  showLogin();
+};
+
+const checkSession = async () => {
+  const response = await fetch("/api/auth/me");
+  if (response.ok) {
+    const data = await response.json();
+    showAccount(data.username);
+  }
+};
+
+elements.loginBtn.addEventListener("click", () => submit("login"));
+elements.registerBtn.addEventListener("click", () => submit("register"));
+elements.logoutBtn.addEventListener("click", logout);
+elements.saveProfileBtn.addEventListener("click", saveProfile);
+
+checkSession();
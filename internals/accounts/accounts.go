@@ -0,0 +1,255 @@
+// Package accounts keeps the player accounts and their login sessions.
+// Passwords are stored as bcrypt hashes and sessions as SHA-256 hashes of
+// the random token handed to the browser, so the accounts file never holds
+// anything that could be replayed.
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/jsonfile"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionTTL is how long a login lasts.
+const SessionTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidUsername = errors.New("usernames must be 4 to 20 letters, digits, \"-\" or \"_\"")
+	ErrInvalidPassword = errors.New("passwords must be between 8 and 72 characters")
+	ErrUsernameTaken   = errors.New("this username is already taken")
+	ErrBadCredentials  = errors.New("wrong username or password")
+	ErrNoAccount       = errors.New("no account with this username")
+)
+
+var validUsername = regexp.MustCompile(`^[A-Za-z0-9_-]{4,20}$`)
+
+// dummyHash is a bcrypt hash, at the default cost, that the password of
+// unknown usernames is compared against, so a failed login takes as long
+// whether the account exists or not.
+var dummyHash = []byte("$2a$10$BBtaesQ9ersl4fUVCW59bubCKJtxE97N6hSQYxy4RWP.qTwuAGeKe")
+
+// Account is a registered player.
+type Account struct {
+	Username     string    `json:"username"`
+	PasswordHash []byte    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+	Admin        bool      `json:"admin,omitempty"` // may use the admin API
+}
+
+type session struct {
+	Username string    `json:"username"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Store holds the accounts, keyed by lower case username, and the open
+// sessions, keyed by token hash. It is safe for concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	accounts map[string]*Account
+	sessions map[string]*session
+	dirty    bool
+}
+
+// storeFile is the layout of the file written by Save.
+type storeFile struct {
+	Accounts map[string]*Account `json:"accounts"`
+	Sessions map[string]*session `json:"sessions"`
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		accounts: make(map[string]*Account),
+		sessions: make(map[string]*session),
+	}
+}
+
+// Load reads a store saved by Save. A missing file gives an empty store.
+func Load(file string) (*Store, error) {
+	s := New()
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved storeFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	if saved.Accounts != nil {
+		s.accounts = saved.Accounts
+	}
+	if saved.Sessions != nil {
+		s.sessions = saved.Sessions
+	}
+	return s, nil
+}
+
+// Save writes the store to file if it changed since the last save, dropping
+// expired sessions. The file is replaced atomically and only readable by
+// its owner.
+func (s *Store) Save(file string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	now := time.Now()
+	for key, session := range s.sessions {
+		if now.After(session.Expires) {
+			delete(s.sessions, key)
+		}
+	}
+	if err := jsonfile.Write(file, storeFile{s.accounts, s.sessions}, 0o600); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Register creates an account. Usernames are unique regardless of case.
+func (s *Store) Register(username, password string) (Account, error) {
+	if !validUsername.MatchString(username) {
+		return Account{}, ErrInvalidUsername
+	}
+	if len(password) < 8 || len(password) > 72 {
+		return Account{}, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return Account{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(username)
+	if _, exists := s.accounts[key]; exists {
+		return Account{}, ErrUsernameTaken
+	}
+
+	account := &Account{
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+	s.accounts[key] = account
+	s.dirty = true
+	return *account, nil
+}
+
+// Authenticate checks a username and password.
+func (s *Store) Authenticate(username, password string) (Account, error) {
+	s.mu.Lock()
+	account, ok := s.accounts[strings.ToLower(username)]
+	s.mu.Unlock()
+
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return Account{}, ErrBadCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password)); err != nil {
+		return Account{}, ErrBadCredentials
+	}
+	return *account, nil
+}
+
+// Lookup returns the account with the given username, ignoring case.
+func (s *Store) Lookup(username string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[strings.ToLower(username)]
+	if !ok {
+		return Account{}, false
+	}
+	return *account, true
+}
+
+// SetAdmin grants or revokes the admin role of the account with the given
+// username, ignoring case.
+func (s *Store) SetAdmin(username string, admin bool) (Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[strings.ToLower(username)]
+	if !ok {
+		return Account{}, ErrNoAccount
+	}
+	if account.Admin != admin {
+		account.Admin = admin
+		s.dirty = true
+	}
+	return *account, nil
+}
+
+// NewSession opens a session for the account and returns its token and
+// expiry time.
+func (s *Store) NewSession(account Account) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(SessionTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[hashToken(token)] = &session{
+		Username: account.Username,
+		Expires:  expires,
+	}
+	s.dirty = true
+	return token, expires, nil
+}
+
+// Session returns the account logged in with the token, if the session is
+// still open.
+func (s *Store) Session(token string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[hashToken(token)]
+	if !ok || time.Now().After(session.Expires) {
+		return Account{}, false
+	}
+	account, ok := s.accounts[strings.ToLower(session.Username)]
+	if !ok {
+		return Account{}, false
+	}
+	return *account, true
+}
+
+// EndSession closes the session of the token.
+func (s *Store) EndSession(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hashToken(token)
+	if _, ok := s.sessions[key]; ok {
+		delete(s.sessions, key)
+		s.dirty = true
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package accounts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegister(t *testing.T) {
+	s := New()
+	tests := []struct {
+		username, password string
+		want               error
+	}{
+		{"alice", "correct horse", nil},
+		{"ALICE", "another password", ErrUsernameTaken},
+		{"bob", "correct horse", ErrInvalidUsername},
+		{"bob the builder", "correct horse", ErrInvalidUsername},
+		{"bob_the-builder", "short", ErrInvalidPassword},
+		{"bob_the-builder", string(make([]byte, 73)), ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		if _, err := s.Register(tt.username, tt.password); !errors.Is(err, tt.want) {
+			t.Errorf("Register(%q) error %v, want %v", tt.username, err, tt.want)
+		}
+	}
+
+	account, ok := s.Lookup("Alice")
+	if !ok || account.Username != "alice" {
+		t.Fatalf("Lookup(Alice) = %+v, %v", account, ok)
+	}
+	if string(account.PasswordHash) == "correct horse" {
+		t.Error("password stored in the clear")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := New()
+	if _, err := s.Register("alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	if account, err := s.Authenticate("ALICE", "correct horse"); err != nil || account.Username != "alice" {
+		t.Errorf("Authenticate with the password = %+v, %v", account, err)
+	}
+	if _, err := s.Authenticate("alice", "wrong horse"); err != ErrBadCredentials {
+		t.Errorf("Authenticate with a wrong password: %v, want ErrBadCredentials", err)
+	}
+	if _, err := s.Authenticate("nobody", "correct horse"); err != ErrBadCredentials {
+		t.Errorf("Authenticate of an unknown user: %v, want ErrBadCredentials", err)
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	// Unknown usernames must cost a bcrypt comparison as slow as a real one
+	cost, err := bcrypt.Cost(dummyHash)
+	if err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("dummy hash cost %d, %v, want %d", cost, err, bcrypt.DefaultCost)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := New()
+	account, _ := s.Register("alice", "correct horse")
+
+	token, expires, err := s.NewSession(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(expires); until < SessionTTL-time.Minute || until > SessionTTL {
+		t.Errorf("session expires in %v, want %v", until, SessionTTL)
+	}
+	if got, ok := s.Session(token); !ok || got.Username != "alice" {
+		t.Errorf("Session = %+v, %v", got, ok)
+	}
+	if _, ok := s.Session(token + "0"); ok {
+		t.Error("Session accepted an unknown token")
+	}
+	for key := range s.sessions {
+		if key == token {
+			t.Error("session stored under the token itself instead of its hash")
+		}
+	}
+
+	s.EndSession(token)
+	if _, ok := s.Session(token); ok {
+		t.Error("Session accepted a token after EndSession")
+	}
+
+	token, _, _ = s.NewSession(account)
+	s.sessions[hashToken(token)].Expires = time.Now().Add(-time.Second)
+	if _, ok := s.Session(token); ok {
+		t.Error("Session accepted an expired token")
+	}
+}
+
+func TestSetAdmin(t *testing.T) {
+	s := New()
+	s.Register("alice", "correct horse")
+
+	if account, err := s.SetAdmin("ALICE", true); err != nil || !account.Admin {
+		t.Errorf("SetAdmin = %+v, %v", account, err)
+	}
+	if account, _ := s.Lookup("alice"); !account.Admin {
+		t.Error("admin role not kept")
+	}
+	if _, err := s.SetAdmin("nobody", true); err != ErrNoAccount {
+		t.Errorf("SetAdmin of an unknown user: %v, want ErrNoAccount", err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "accounts.json")
+
+	s := New()
+	account, _ := s.Register("alice", "correct horse")
+	live, _, _ := s.NewSession(account)
+	expired, _, _ := s.NewSession(account)
+	s.sessions[hashToken(expired)].Expires = time.Now().Add(-time.Second)
+	if err := s.Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("accounts file mode %v, want 0600", perm)
+	}
+
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Authenticate("alice", "correct horse"); err != nil {
+		t.Errorf("Authenticate after Load: %v", err)
+	}
+	if _, ok := loaded.Session(live); !ok {
+		t.Error("open session lost by Save")
+	}
+	if len(loaded.sessions) != 1 {
+		t.Errorf("loaded %d sessions, want the expired one dropped", len(loaded.sessions))
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Lookup("alice"); ok {
+		t.Error("empty store has accounts")
+	}
+}
@@ -0,0 +1,160 @@
+package internals
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/achievements"
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+const badgesFile = "./data/badges.json"
+
+var (
+	badgeEngine *achievements.Engine
+	badgesOnce  sync.Once
+)
+
+// loadBadges reads the badge definitions from data/badges.json on first use
+// and returns the cached engine afterwards. Besides the groups of the file,
+// rules can refer to "eurovision", the countries that entered the Eurovision
+// Song Contest, and to continents in lower case, e.g. "europe".
+func loadBadges() *achievements.Engine {
+	badgesOnce.Do(func() {
+		groups := make(map[string][]string)
+		if countries, err := loadCatalog(); err == nil {
+			for _, country := range countries.Countries {
+				if country.EurovisionDebut != 0 {
+					groups["eurovision"] = append(groups["eurovision"], country.Code)
+				}
+				if country.Continent != "" {
+					continent := strings.ToLower(country.Continent)
+					groups[continent] = append(groups[continent], country.Code)
+				}
+			}
+		}
+
+		var err error
+		badgeEngine, err = achievements.Load(badgesFile, groups)
+		if err != nil {
+			slog.Error("Failed to load badges", "error", err)
+			badgeEngine = achievements.New()
+		}
+	})
+	return badgeEngine
+}
+
+// answerStreak returns how many questions up to questionIndex the player
+// answered correctly in a row. Questions left unanswered break the streak.
+func answerStreak(player *game.Player, questionIndex int) int {
+	correct := make(map[int]bool)
+	for _, answer := range player.Answers {
+		correct[answer.QuestionIndex] = answer.Correct
+	}
+
+	streak := 0
+	for i := questionIndex; i >= 0 && correct[i]; i-- {
+		streak++
+	}
+	return streak
+}
+
+// roomWinners returns the players who won the room: the last player standing
+// in elimination rooms, the top of the scoreboard after a jury finale, and
+// the best score otherwise. Nobody wins a room where nobody scored. The
+// caller must hold mu.
+func roomWinners(room *game.Room) []*game.Player {
+	if room.GameMode == "ELIMINATION" {
+		if winner := eliminationWinner(room); winner != nil {
+			return []*game.Player{winner}
+		}
+		return nil
+	}
+
+	var winners []*game.Player
+	best := 0
+	for _, player := range roomParticipants(room) {
+		points := player.Score
+		if room.FinaleDone {
+			points = player.JuryPoints
+		}
+		switch {
+		case points > best:
+			best = points
+			winners = []*game.Player{player}
+		case points == best && points > 0:
+			winners = append(winners, player)
+		}
+	}
+	return winners
+}
+
+// checkBadges gives the account the badges the event earns it and returns
+// them.
+func checkBadges(username string, event achievements.Event) []achievements.Badge {
+	engine := loadBadges()
+	store := loadProfiles()
+
+	earned := engine.Check(event, store.Get(username))
+	if len(earned) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(earned))
+	for i, badge := range earned {
+		ids[i] = badge.ID
+	}
+
+	var awarded []achievements.Badge
+	for _, id := range store.Award(username, ids, time.Now()) {
+		if badge, ok := engine.Badge(id); ok {
+			awarded = append(awarded, badge)
+		}
+	}
+	return awarded
+}
+
+// awardBadges checks an event of a player against the badge rules and
+// announces the badges they earn to the room with a "badge_awarded" event.
+// Only logged in players earn badges.
+//
+// Parameters:
+//   - room: Pointer to the Room instance the event happened in
+//   - player: The player the event is about
+//   - event: What the player did, see achievements.Event
+func awardBadges(room *game.Room, player *game.Player, event achievements.Event) {
+	if player.Account == "" {
+		return
+	}
+
+	for _, badge := range checkBadges(player.Account, event) {
+		broadcastToRoom(room, map[string]interface{}{
+			"event": "badge_awarded",
+			"data": map[string]interface{}{
+				"id":       player.ID,
+				"username": player.Username,
+				"badge":    badge,
+			},
+		})
+	}
+}
+
+// badgesHandler lists the badges players can earn.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: The badges with their rules, in the order of data/badges.json
+func badgesHandler(w http.ResponseWriter, r *http.Request) {
+	badges := loadBadges().Badges()
+	if badges == nil {
+		badges = []achievements.Badge{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(badges)
+}
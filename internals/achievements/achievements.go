@@ -0,0 +1,206 @@
+// Package achievements awards badges for what players do in games. The
+// badges are declared in a data file (data/badges.json), each with a rule
+// checked against game events and the player's profile.
+package achievements
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adimail/fun-with-flags/internals/profiles"
+)
+
+// Event types
+const (
+	AnswerEvent = "answer" // a player answered a question
+	GameEvent   = "game"   // a game ended and its result was added to the profile
+)
+
+// Rule types and the event they are checked on
+var ruleEvents = map[string]string{
+	// Count correct answers in a row within a game
+	"streak": AnswerEvent,
+	// Every flag of Group (or Codes) answered correctly at least Count
+	// times, over all games, in questions showing the flag (see
+	// profiles.Profile.Flags)
+	"flags_correct": GameEvent,
+	// Win a room of at least MinPlayers players
+	"room_win": GameEvent,
+}
+
+// Rule is the condition to earn a badge.
+type Rule struct {
+	Type       string   `json:"type"` // see ruleEvents
+	Count      int      `json:"count,omitempty"`
+	Group      string   `json:"group,omitempty"` // named list of ISO codes
+	Codes      []string `json:"codes,omitempty"` // ISO codes, instead of Group
+	MinPlayers int      `json:"min_players,omitempty"`
+}
+
+// Badge is one achievement players can earn.
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"` // an emoji
+	Rule        Rule   `json:"rule"`
+}
+
+// Event is something a player did that may earn badges.
+type Event struct {
+	Type string // AnswerEvent or GameEvent
+
+	// AnswerEvent: correct answers in a row in the current game
+	Streak int
+
+	// GameEvent: whether the player won and how many played
+	Won     bool
+	Players int
+}
+
+// Engine checks events against the badge rules. It is read only once
+// loaded and safe for concurrent use.
+type Engine struct {
+	badges []Badge
+	groups map[string][]string // ISO codes by group name
+}
+
+// New returns an engine without badges.
+func New() *Engine {
+	return &Engine{groups: map[string][]string{}}
+}
+
+// Load reads the badge definitions from file, a JSON object with "badges",
+// the list of badges, and "groups", lists of ISO codes by name that rules can
+// refer to. The groups given here are added to those of the file, which take
+// precedence; callers use them for groups known from the catalog, such as
+// "eurovision".
+func Load(file string, groups map[string][]string) (*Engine, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved struct {
+		Groups map[string][]string `json:"groups"`
+		Badges []Badge             `json:"badges"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	e := New()
+	for name, codes := range groups {
+		e.groups[name] = codes
+	}
+	for name, codes := range saved.Groups {
+		e.groups[name] = codes
+	}
+
+	seen := make(map[string]bool)
+	for _, badge := range saved.Badges {
+		if err := e.validate(badge); err != nil {
+			return nil, fmt.Errorf("%s: badge %q: %w", file, badge.ID, err)
+		}
+		if seen[badge.ID] {
+			return nil, fmt.Errorf("%s: duplicate badge %q", file, badge.ID)
+		}
+		seen[badge.ID] = true
+		e.badges = append(e.badges, badge)
+	}
+	return e, nil
+}
+
+// validate checks that a badge can be earned.
+func (e *Engine) validate(badge Badge) error {
+	if badge.ID == "" || badge.Name == "" {
+		return fmt.Errorf("id and name are required")
+	}
+
+	rule := badge.Rule
+	if _, ok := ruleEvents[rule.Type]; !ok {
+		return fmt.Errorf("unknown rule type %q", rule.Type)
+	}
+
+	switch rule.Type {
+	case "streak":
+		if rule.Count < 1 {
+			return fmt.Errorf("count must be at least 1")
+		}
+	case "flags_correct":
+		if len(e.codes(rule)) == 0 {
+			return fmt.Errorf("group %q has no flags", rule.Group)
+		}
+	case "room_win":
+		if rule.MinPlayers < 1 {
+			return fmt.Errorf("min_players must be at least 1")
+		}
+	}
+	return nil
+}
+
+// codes returns the ISO codes a rule is about.
+func (e *Engine) codes(rule Rule) []string {
+	if len(rule.Codes) > 0 {
+		return rule.Codes
+	}
+	return e.groups[rule.Group]
+}
+
+// Badges returns every badge, in the order of the data file.
+func (e *Engine) Badges() []Badge {
+	return e.badges
+}
+
+// Badge returns the badge with the given ID.
+func (e *Engine) Badge(id string) (Badge, bool) {
+	for _, badge := range e.badges {
+		if badge.ID == id {
+			return badge, true
+		}
+	}
+	return Badge{}, false
+}
+
+// Check returns the badges the event earns the player that they don't
+// hold yet. The profile must already include the game for a GameEvent.
+func (e *Engine) Check(event Event, profile profiles.Profile) []Badge {
+	var earned []Badge
+	for _, badge := range e.badges {
+		if _, held := profile.Badges[badge.ID]; held {
+			continue
+		}
+		if ruleEvents[badge.Rule.Type] != event.Type {
+			continue
+		}
+		if e.met(badge.Rule, event, profile) {
+			earned = append(earned, badge)
+		}
+	}
+	return earned
+}
+
+// met reports whether the event satisfies the rule.
+func (e *Engine) met(rule Rule, event Event, profile profiles.Profile) bool {
+	switch rule.Type {
+	case "streak":
+		return event.Streak >= rule.Count
+	case "flags_correct":
+		times := rule.Count
+		if times < 1 {
+			times = 1
+		}
+		for _, code := range e.codes(rule) {
+			tally, ok := profile.Flags[strings.ToUpper(code)]
+			if !ok || tally.Correct < times {
+				return false
+			}
+		}
+		return true
+	case "room_win":
+		return event.Won && event.Players >= rule.MinPlayers
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package achievements
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/profiles"
+)
+
+// load writes a badge file and loads it.
+func load(t *testing.T, content string, groups map[string][]string) (*Engine, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "badges.json")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(file, groups)
+}
+
+const badgesFixture = `{
+  "groups": {"nordic": ["DK", "NO", "SE"], "eurovision": ["FR"]},
+  "badges": [
+    {"id": "streak-3", "name": "Three", "rule": {"type": "streak", "count": 3}},
+    {"id": "nordic", "name": "Nordic", "rule": {"type": "flags_correct", "group": "nordic", "count": 2}},
+    {"id": "pair", "name": "Pair", "rule": {"type": "flags_correct", "codes": ["fr", "de"]}},
+    {"id": "euro", "name": "Euro", "rule": {"type": "flags_correct", "group": "eurovision"}},
+    {"id": "win-3", "name": "Crowd", "rule": {"type": "room_win", "min_players": 3}}
+  ]
+}`
+
+func TestLoad(t *testing.T) {
+	e, err := load(t, badgesFixture, map[string][]string{"eurovision": {"IT"}, "europe": {"FR", "DE"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Badges()) != 5 || e.Badges()[0].ID != "streak-3" {
+		t.Errorf("badges = %v, want the 5 of the file in order", e.Badges())
+	}
+	if badge, ok := e.Badge("win-3"); !ok || badge.Rule.MinPlayers != 3 {
+		t.Errorf("Badge(win-3) = %+v, %v", badge, ok)
+	}
+	if _, ok := e.Badge("missing"); ok {
+		t.Error("Badge found an unknown ID")
+	}
+	// Groups of the file take precedence over those given
+	if got := e.groups["eurovision"]; len(got) != 1 || got[0] != "FR" {
+		t.Errorf("eurovision group = %v, want the file's", got)
+	}
+	if got := e.groups["europe"]; len(got) != 2 {
+		t.Errorf("europe group = %v, want the one given", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, badges, want string
+	}{
+		{"no id", `{"name": "A", "rule": {"type": "streak", "count": 1}}`, "id and name are required"},
+		{"no name", `{"id": "a", "rule": {"type": "streak", "count": 1}}`, "id and name are required"},
+		{"unknown rule", `{"id": "a", "name": "A", "rule": {"type": "jump"}}`, `unknown rule type "jump"`},
+		{"streak without count", `{"id": "a", "name": "A", "rule": {"type": "streak"}}`, "count must be at least 1"},
+		{"empty group", `{"id": "a", "name": "A", "rule": {"type": "flags_correct", "group": "atlantis"}}`, `group "atlantis" has no flags`},
+		{"win without players", `{"id": "a", "name": "A", "rule": {"type": "room_win"}}`, "min_players must be at least 1"},
+		{"duplicate", `{"id": "a", "name": "A", "rule": {"type": "streak", "count": 1}},
+			{"id": "a", "name": "B", "rule": {"type": "streak", "count": 2}}`, `duplicate badge "a"`},
+	}
+	for _, tt := range tests {
+		_, err := load(t, `{"badges": [`+tt.badges+`]}`, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := load(t, `{"badges": `, nil); err == nil {
+		t.Error("Load of invalid JSON succeeded")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json"), nil); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	e, err := load(t, badgesFixture, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := func(held []string, correct map[string]int) profiles.Profile {
+		p := profiles.Profile{Flags: map[string]*profiles.Tally{}, Badges: map[string]time.Time{}}
+		for _, id := range held {
+			p.Badges[id] = time.Now()
+		}
+		for code, n := range correct {
+			p.Flags[code] = &profiles.Tally{Answers: n, Correct: n}
+		}
+		return p
+	}
+	ids := func(badges []Badge) string {
+		var got []string
+		for _, badge := range badges {
+			got = append(got, badge.ID)
+		}
+		return strings.Join(got, ",")
+	}
+
+	tests := []struct {
+		name    string
+		event   Event
+		profile profiles.Profile
+		want    string
+	}{
+		{"short streak", Event{Type: AnswerEvent, Streak: 2}, profile(nil, nil), ""},
+		{"streak", Event{Type: AnswerEvent, Streak: 3}, profile(nil, nil), "streak-3"},
+		{"streak held", Event{Type: AnswerEvent, Streak: 5}, profile([]string{"streak-3"}, nil), ""},
+		{"game rules wait for a game", Event{Type: AnswerEvent, Streak: 1}, profile(nil, map[string]int{"FR": 1, "DE": 1}), ""},
+		{"group once", Event{Type: GameEvent}, profile(nil, map[string]int{"DK": 1, "NO": 1, "SE": 1}), ""},
+		{"group twice", Event{Type: GameEvent}, profile(nil, map[string]int{"DK": 2, "NO": 2, "SE": 3}), "nordic"},
+		{"group missing a flag", Event{Type: GameEvent}, profile(nil, map[string]int{"DK": 2, "NO": 2}), ""},
+		{"lower case codes", Event{Type: GameEvent}, profile(nil, map[string]int{"FR": 1, "DE": 1}), "pair,euro"},
+		{"small room won", Event{Type: GameEvent, Won: true, Players: 2}, profile(nil, nil), ""},
+		{"room won", Event{Type: GameEvent, Won: true, Players: 3}, profile(nil, nil), "win-3"},
+		{"room lost", Event{Type: GameEvent, Players: 9}, profile(nil, nil), ""},
+	}
+	for _, tt := range tests {
+		if got := ids(e.Check(tt.event, tt.profile)); got != tt.want {
+			t.Errorf("%s: Check = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
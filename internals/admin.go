@@ -0,0 +1,405 @@
+package internals
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/adimail/fun-with-flags/internals/accounts"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/mux"
+)
+
+// adminTokenEnv names the environment variable holding the admin token. The
+// admin API is also open to accounts with the admin flag, which the token
+// can grant (see adminAccountHandler). Without a token only those accounts
+// are admins.
+const adminTokenEnv = "ADMIN_TOKEN"
+
+// openSockets counts the WebSocket connections currently open, see
+// HandleWebSocket.
+var openSockets atomic.Int64
+
+// requireAdmin checks that the request comes from an admin: it carries the
+// admin token as "Authorization: Bearer <token>", or is logged in to an
+// account with the admin flag. Otherwise it writes a 401 or 403 response
+// and returns false.
+//
+// Returns:
+//   - string: Who is acting, "token" or the account name, for the logs
+//   - bool: true if the request may use the admin API
+func requireAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if token := os.Getenv(adminTokenEnv); token != "" {
+		given, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if found && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			return "token", true
+		}
+	}
+
+	account, ok := currentAccount(r)
+	if ok && account.Admin {
+		return account.Username, true
+	}
+
+	status, message := http.StatusUnauthorized, "Admin token or login required"
+	if ok {
+		status, message = http.StatusForbidden, "This account is not an admin"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	return "", false
+}
+
+// adminRoom returns the room of the request's {id}, or writes a 404 response.
+func adminRoom(w http.ResponseWriter, r *http.Request) (*game.Room, bool) {
+	mu.Lock()
+	room, ok := rooms[mux.Vars(r)["id"]]
+	mu.Unlock()
+
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Room not found"})
+	}
+	return room, ok
+}
+
+// closeRoom disconnects everyone from a room and removes it, unless another
+// room has taken its code since.
+func closeRoom(room *game.Room) {
+	closeRoomConnections(room)
+
+	mu.Lock()
+	if rooms[room.Code] == room {
+		delete(rooms, room.Code)
+	}
+	mu.Unlock()
+}
+
+// adminRoomHandler returns everything about a room: its settings, the
+// players with their answers, the spectators, the open round and the
+// questions with their answers.
+//
+// HTTP Method: GET
+// URL Parameters:
+//   - id: The room code
+//
+// Response:
+//   - 200: The room details
+//   - 401, 403: Not an admin (see requireAdmin)
+//   - 404: Room not found
+func adminRoomHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+	room, ok := adminRoom(w, r)
+	if !ok {
+		return
+	}
+
+	mu.Lock()
+	players := []map[string]interface{}{}
+	for _, player := range room.Players {
+		players = append(players, map[string]interface{}{
+			"id":         player.ID,
+			"username":   player.Username,
+			"account":    player.Account,
+			"team":       player.Team,
+			"score":      player.Score,
+			"answers":    len(player.Answers),
+			"completed":  player.Completed,
+			"lives":      player.Lives,
+			"eliminated": player.Eliminated,
+		})
+	}
+	spectators := []string{}
+	for _, spectator := range room.Spectators {
+		spectators = append(spectators, spectator.Username)
+	}
+	questions := make([]*game.Question, len(room.Questions))
+	for i := range questions {
+		questions[i] = room.Questions[strconv.Itoa(i)]
+	}
+	round := -1
+	if room.Round != nil {
+		round = room.Round.Index
+	}
+	details := map[string]interface{}{
+		"code":          room.Code,
+		"host":          room.Hostname,
+		"gamemode":      room.GameMode,
+		"timeLimit":     room.TimeLimit,
+		"gameStarted":   room.Start,
+		"synchronized":  room.Synchronized,
+		"round":         round,
+		"teams":         room.Teams,
+		"questionTypes": room.QuestionTypes,
+		"flagVariant":   room.FlagVariant,
+		"difficulty":    room.Difficulty,
+		"packId":        room.PackID,
+		"region":        room.Region,
+		"mapRetries":    room.MapRetries,
+		"juryFinale":    room.JuryFinale,
+		"players":       players,
+		"spectators":    spectators,
+		"questions":     questions,
+	}
+	if room.Start {
+		details["startedAt"] = room.StartedAt.Format(time.RFC3339)
+	}
+	if room.Audit != nil {
+		details["game"] = room.Audit.ID // see gameLogHandler
+	}
+	mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// adminEndRoomHandler ends a game before its time: the results so far are
+// recorded and sent to the room with "time_over", as when the time limit is
+// reached, then everyone is disconnected and the room is removed.
+//
+// HTTP Method: POST
+// URL Parameters:
+//   - id: The room code
+//
+// Response:
+//   - 200: {"code", "ended"}
+//   - 401, 403: Not an admin (see requireAdmin)
+//   - 404: Room not found
+func adminEndRoomHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	room, ok := adminRoom(w, r)
+	if !ok {
+		return
+	}
+
+	mu.Lock()
+	started := room.Start
+	mu.Unlock()
+	if started {
+		recordGameResults(room)
+	}
+	mu.Lock()
+	results := finalResults(room)
+	mu.Unlock()
+	broadcastToRoom(room, map[string]interface{}{
+		"event": "time_over",
+		"data":  results,
+	})
+	closeRoom(room)
+	requestLogger(r).Info("Room ended by admin", "room", room.Code, "admin", admin)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":  room.Code,
+		"ended": true,
+	})
+}
+
+// adminDeleteRoomHandler removes a room without recording any results. The
+// room is told with a "room_closed" event before everyone is disconnected.
+//
+// HTTP Method: DELETE
+// URL Parameters:
+//   - id: The room code
+//
+// Response:
+//   - 200: {"code", "deleted"}
+//   - 401, 403: Not an admin (see requireAdmin)
+//   - 404: Room not found
+func adminDeleteRoomHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	room, ok := adminRoom(w, r)
+	if !ok {
+		return
+	}
+
+	broadcastToRoom(room, map[string]interface{}{
+		"event": "room_closed",
+		"data": map[string]interface{}{
+			"reason": "The room was closed by an admin",
+		},
+	})
+	closeRoom(room)
+	requestLogger(r).Info("Room deleted by admin", "room", room.Code, "admin", admin)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    room.Code,
+		"deleted": true,
+	})
+}
+
+// announceHandler sends an announcement to every open socket, players and
+// spectators of all rooms, as an "announcement" event.
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - message: 1-200 characters
+//
+// Response:
+//   - 200: {"rooms", "sockets"}: how many got the announcement
+//   - 400: Invalid message
+//   - 401, 403: Not an admin (see requireAdmin)
+func announceHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" || utf8.RuneCountInString(req.Message) > 200 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Message must be between 1 and 200 characters"})
+		return
+	}
+
+	mu.Lock()
+	targets := make([]*game.Room, 0, len(rooms))
+	for _, room := range rooms {
+		targets = append(targets, room)
+	}
+	mu.Unlock()
+
+	message := map[string]interface{}{
+		"event": "announcement",
+		"data": map[string]interface{}{
+			"message": req.Message,
+		},
+	}
+	sockets := 0
+	for _, room := range targets {
+		mu.Lock()
+		sockets += len(room.Players) + len(room.Spectators)
+		mu.Unlock()
+		broadcastToRoom(room, message)
+	}
+	requestLogger(r).Info("Announcement sent", "admin", admin, "sockets", sockets, "message", req.Message)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rooms":   len(targets),
+		"sockets": sockets,
+	})
+}
+
+// connectionsHandler returns the live connection counts: the WebSocket
+// connections open, and the players and spectators of each room.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"sockets", "players", "spectators", "rooms": [{"code",
+//     "players", "spectators", "gameStarted"}]}
+//   - 401, 403: Not an admin (see requireAdmin)
+func connectionsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	mu.Lock()
+	players, spectators := 0, 0
+	perRoom := []map[string]interface{}{}
+	for _, room := range rooms {
+		players += len(room.Players)
+		spectators += len(room.Spectators)
+		perRoom = append(perRoom, map[string]interface{}{
+			"code":        room.Code,
+			"players":     len(room.Players),
+			"spectators":  len(room.Spectators),
+			"gameStarted": room.Start,
+		})
+	}
+	mu.Unlock()
+	sort.Slice(perRoom, func(i, j int) bool {
+		return perRoom[i]["code"].(string) < perRoom[j]["code"].(string)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sockets":    openSockets.Load(),
+		"players":    players,
+		"spectators": spectators,
+		"rooms":      perRoom,
+	})
+}
+
+// adminAccountHandler grants or revokes the admin role of an account.
+//
+// HTTP Method: PUT
+// Content-Type: application/json
+// URL Parameters:
+//   - username: The account
+//
+// Request Body:
+//   - admin: true to grant the role, false to revoke it
+//
+// Response:
+//   - 200: {"username", "admin"}
+//   - 400: Invalid request
+//   - 401, 403: Not an admin (see requireAdmin)
+//   - 404: No such account
+func adminAccountHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Admin *bool `json:"admin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Admin == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: `Give "admin": true or false`})
+		return
+	}
+
+	account, err := loadAccounts().SetAdmin(mux.Vars(r)["username"], *req.Admin)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, accounts.ErrNoAccount) {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+	requestLogger(r).Info("Admin role changed", "username", account.Username, "admin_role", account.Admin, "admin", admin)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": account.Username,
+		"admin":    account.Admin,
+	})
+}
@@ -0,0 +1,317 @@
+package internals
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/audit"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/mux"
+)
+
+// auditDir holds the audit log of every game, one file per room.
+const auditDir = "./data/audit"
+
+// maxReplaySpeed caps how much faster than the game a replay can run.
+const maxReplaySpeed = 100
+
+// Limits of the audit logs on disk, see pruneAudit. A game of 9 players
+// takes a few hundred kilobytes.
+const (
+	maxAuditFileSize  = 4 << 20   // per game, later entries are dropped
+	maxAuditTotalSize = 512 << 20 // for all games, the oldest are removed
+	maxAuditAge       = 30 * 24 * time.Hour
+)
+
+// openAudit starts the audit log of a new room. Games are played without a
+// log if it can't be opened.
+func openAudit(room *game.Room) *audit.Log {
+	auditLog, err := audit.Open(auditDir, audit.NewID(room.Code, time.Now()))
+	if err != nil {
+		slog.Error("Failed to open audit log", "room", room.Code, "error", err)
+		return nil
+	}
+	auditLog.MaxSize = maxAuditFileSize
+	return auditLog
+}
+
+// pruneAudit removes the audit logs older than maxAuditAge, then the oldest
+// ones until all take at most maxAuditTotalSize. Logs of games still being
+// played are kept.
+func pruneAudit() {
+	removed, err := audit.Prune(auditDir, time.Now(), maxAuditAge, maxAuditTotalSize, liveGame)
+	if err != nil {
+		slog.Error("Failed to prune audit logs", "error", err)
+	}
+	if removed > 0 {
+		slog.Info("Pruned audit logs", "removed", removed)
+	}
+}
+
+// auditMessage appends a message the server sent to the room's audit log.
+//
+// Parameters:
+//   - room: Pointer to the Room instance
+//   - to: ID of the player the message was sent to, empty for broadcasts
+//   - message: The message sent, with its "event" and "data"
+func auditMessage(room *game.Room, to string, message interface{}) {
+	if room.Audit == nil {
+		return
+	}
+
+	entry := audit.Entry{To: to}
+	var data interface{} = message
+	if m, ok := message.(map[string]interface{}); ok {
+		entry.Event, _ = m["event"].(string)
+		data = m["data"]
+	}
+	appendAudit(room, entry, data)
+}
+
+// auditReceived appends a message a player sent to the room's audit log.
+// Only the events the /ws loop handles (see wsEvents) are logged, anything
+// else a client sends is ignored there too.
+func auditReceived(room *game.Room, from, event string, data interface{}) {
+	if room.Audit == nil || !wsEvents[event] {
+		return
+	}
+	appendAudit(room, audit.Entry{From: from, Event: event}, data)
+}
+
+func appendAudit(room *game.Room, entry audit.Entry, data interface{}) {
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			slog.Warn("Failed to encode audit entry", "room", room.Code, "event", entry.Event, "error", err)
+			return
+		}
+		entry.Data = raw
+	}
+	if err := room.Audit.Append(entry); errors.Is(err, audit.ErrFull) {
+		slog.Warn("Audit log is full, dropping later entries", "room", room.Code, "game", room.Audit.ID)
+	} else if err != nil {
+		slog.Warn("Failed to write audit log", "room", room.Code, "game", room.Audit.ID, "error", err)
+	}
+}
+
+// liveGame reports whether the game of an audit log is still being played.
+func liveGame(id string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, room := range rooms {
+		if room.Audit != nil && room.Audit.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// auditError writes the response for an error reading an audit log.
+func auditError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, audit.ErrNoLog):
+		status = http.StatusNotFound
+	case errors.Is(err, audit.ErrInvalidID):
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+}
+
+// gamesHandler lists the games with an audit log, the most recent first.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"games": [{"id", "room", "createdAt", "updatedAt", "size",
+//     "live"}]}, "live" being true while the game is played
+//   - 401, 403: Not an admin (see requireAdmin)
+func gamesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	games, err := audit.List(auditDir)
+	if err != nil {
+		auditError(w, err)
+		return
+	}
+
+	list := make([]map[string]interface{}, 0, len(games))
+	for _, g := range games {
+		list = append(list, map[string]interface{}{
+			"id":        g.ID,
+			"room":      g.Room,
+			"createdAt": g.CreatedAt.Format(time.RFC3339),
+			"updatedAt": g.UpdatedAt.Format(time.RFC3339),
+			"size":      g.Size,
+			"live":      liveGame(g.ID),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"games": list,
+	})
+}
+
+// gameLogHandler returns the audit log of a game: every event sent to or by
+// its players, in order. Entries have "from" set to the ID of the player
+// who sent them, or "to" to the ID of the only player who got them; other
+// entries went to the whole room.
+//
+// HTTP Method: GET
+// URL Parameters:
+//   - id: The game ID, see gamesHandler
+//
+// Response:
+//   - 200: {"id", "live", "entries": [{"time", "from", "to", "event", "data"}]}
+//   - 400: Invalid game ID
+//   - 401, 403: Not an admin (see requireAdmin)
+//   - 404: No log for this game
+func gameLogHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	entries, err := audit.Read(auditDir, id)
+	if err != nil {
+		auditError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"live":    liveGame(id),
+		"entries": entries,
+	})
+}
+
+// replayHandler streams a finished game back over a WebSocket, waiting
+// between entries as long as the players did, divided by the speed. It
+// sends:
+//   - "replay_start": {"id", "entries", "duration_ms", "speed"}
+//   - "replay_entry": Each entry of the log, as in gameLogHandler
+//   - "replay_end": Once every entry was sent
+//
+// The replay stops when the client closes the connection.
+//
+// HTTP Method: GET
+// URL Parameters:
+//   - id: The game ID, see gamesHandler
+//
+// Query Parameters:
+//   - speed: 1 (default) for real time, up to 100 times faster
+//
+// Response:
+//   - 101: The replay
+//   - 400: Invalid game ID or speed
+//   - 401, 403: Not an admin (see requireAdmin)
+//   - 404: No log for this game
+//   - 409: The game is still being played
+func replayHandler(w http.ResponseWriter, r *http.Request) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	speed := 1.0
+	if value := r.URL.Query().Get("speed"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 1 || parsed > maxReplaySpeed {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Speed must be between 1 and 100"})
+			return
+		}
+		speed = parsed
+	}
+
+	id := mux.Vars(r)["id"]
+	if liveGame(id) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "The game is still being played"})
+		return
+	}
+	entries, err := audit.Read(auditDir, id)
+	if err != nil {
+		auditError(w, err)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		requestLogger(r).Warn("WebSocket upgrade error", "error", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxMessageSize)
+
+	openSockets.Add(1)
+	defer openSockets.Add(-1)
+
+	logger := requestLogger(r).With("game", id)
+	logger.Info("Replay started", "admin", admin, "speed", speed)
+
+	// Reading notices when the client goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var duration time.Duration
+	if len(entries) > 0 {
+		duration = entries[len(entries)-1].Time.Sub(entries[0].Time)
+	}
+	err = conn.WriteJSON(map[string]interface{}{
+		"event": "replay_start",
+		"data": map[string]interface{}{
+			"id":          id,
+			"entries":     len(entries),
+			"duration_ms": duration.Milliseconds(),
+			"speed":       speed,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	for i, entry := range entries {
+		if i > 0 {
+			wait := time.Duration(float64(entry.Time.Sub(entries[i-1].Time)) / speed)
+			select {
+			case <-time.After(wait):
+			case <-closed:
+				logger.Info("Replay stopped by the client", "sent", i)
+				return
+			}
+		}
+
+		err := conn.WriteJSON(map[string]interface{}{
+			"event": "replay_entry",
+			"data":  entry,
+		})
+		if err != nil {
+			logger.Warn("Error sending replay entry", "error", err)
+			return
+		}
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"event": "replay_end",
+	})
+}
@@ -0,0 +1,244 @@
+// Package audit keeps a log of every game: the events the server sent to the
+// room or to one of its players, and those the players sent, each with the
+// time it happened. Each game has a file of its own with one JSON entry per
+// line, only ever appended to, so a game can be reviewed or replayed after
+// its room is gone.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ext is the extension of the log files.
+const ext = ".jsonl"
+
+var (
+	// ErrNoLog is returned for IDs without a log.
+	ErrNoLog = errors.New("no log for this game")
+	// ErrInvalidID is returned for IDs that NewID can't have made.
+	ErrInvalidID = errors.New("invalid game ID")
+	// ErrFull is returned for the first entry a Log drops for being over
+	// its MaxSize.
+	ErrFull = errors.New("game log is full")
+)
+
+// idPattern matches the IDs made by NewID. They become file names, so
+// anything else is refused.
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z]{1,16}-[0-9]{1,20}$`)
+
+// Entry is one event of a game.
+type Entry struct {
+	Time  time.Time       `json:"time"`
+	From  string          `json:"from,omitempty"` // ID of the player who sent the event, empty for the server
+	To    string          `json:"to,omitempty"`   // ID of the only player sent the event, empty for the room
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// Game describes a logged game, see List.
+type Game struct {
+	ID        string    `json:"id"`
+	Room      string    `json:"room"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"` // time of the last entry written
+	Size      int64     `json:"size"`      // in bytes
+}
+
+// NewID returns the ID of a game played in the room of the given code.
+// Room codes are reused once a room is gone, so the ID adds the time the
+// room was created, in Unix milliseconds.
+func NewID(room string, created time.Time) string {
+	return room + "-" + strconv.FormatInt(created.UnixMilli(), 10)
+}
+
+// ValidID reports whether id can be the ID of a game.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Log appends the entries of one game to its file. It is safe for
+// concurrent use. A nil or closed Log drops its entries.
+type Log struct {
+	ID string
+
+	// MaxSize caps the file of the log, in bytes. Entries that don't fit
+	// are dropped, so a client flooding the room can't fill the disk. 0
+	// for no cap.
+	MaxSize int64
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	full   bool
+	closed bool
+}
+
+// Open creates the log of a game in dir, creating dir if needed, or opens it
+// to append more entries.
+func Open(dir, id string) (*Log, error) {
+	if !ValidID(id) {
+		return nil, ErrInvalidID
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, id+ext), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Log{ID: id, file: file, size: info.Size()}, nil
+}
+
+// Append writes an entry, timed now unless it has a time. Once the log is
+// full the first dropped entry returns ErrFull, later ones nil.
+func (l *Log) Append(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.full {
+		return nil
+	}
+	if l.MaxSize > 0 && l.size+int64(len(line))+1 > l.MaxSize {
+		l.full = true
+		return ErrFull
+	}
+	n, err := l.file.Write(append(line, '\n'))
+	l.size += int64(n)
+	return err
+}
+
+// Close closes the file of the log. Later entries are dropped.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	return l.file.Close()
+}
+
+// Read returns the entries of a game in the order they were written.
+func Read(dir, id string) ([]Entry, error) {
+	if !ValidID(id) {
+		return nil, ErrInvalidID
+	}
+	file, err := os.Open(filepath.Join(dir, id+ext))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoLog
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash ends the log
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// List returns the games logged in dir, the most recent first. A missing
+// dir has no games.
+func List(dir string) ([]Game, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Game{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	games := []Game{}
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file.Name(), ext)
+		if !ok || !ValidID(id) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		room, created, _ := strings.Cut(id, "-")
+		millis, _ := strconv.ParseInt(created, 10, 64)
+		games = append(games, Game{
+			ID:        id,
+			Room:      room,
+			CreatedAt: time.UnixMilli(millis),
+			UpdatedAt: info.ModTime(),
+			Size:      info.Size(),
+		})
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].CreatedAt.After(games[j].CreatedAt)
+	})
+	return games, nil
+}
+
+// Prune removes the logs in dir last written more than maxAge before now,
+// then the oldest ones until the rest take at most maxSize bytes. Logs for
+// which keep returns true, such as those of games still played, stay and
+// count towards maxSize. A maxAge or maxSize of 0 means no limit.
+//
+// Returns:
+//   - int: How many logs were removed
+//   - error: Listing or removing the logs failed
+func Prune(dir string, now time.Time, maxAge time.Duration, maxSize int64, keep func(id string) bool) (int, error) {
+	games, err := List(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	var total int64
+	for _, g := range games {
+		tooOld := maxAge > 0 && now.Sub(g.UpdatedAt) > maxAge
+		tooBig := maxSize > 0 && total+g.Size > maxSize
+		if (!tooOld && !tooBig) || keep(g.ID) {
+			total += g.Size
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, g.ID+ext)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,231 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewID(t *testing.T) {
+	id := NewID("AB12", time.UnixMilli(1700000000123))
+	if id != "AB12-1700000000123" || !ValidID(id) {
+		t.Errorf("NewID = %q, valid %v", id, ValidID(id))
+	}
+	for _, id := range []string{"", "AB12", "AB12-", "-123", "../AB12-1", "AB12-1/x", "AB 12-1", "AB12-1x"} {
+		if ValidID(id) {
+			t.Errorf("ValidID(%q) = true", id)
+		}
+	}
+	if _, err := Open(t.TempDir(), "../evil-1"); err != ErrInvalidID {
+		t.Errorf("Open of an invalid ID: %v, want ErrInvalidID", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "audit")
+	log, err := Open(dir, "AB12-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	log.Append(Entry{Time: at, Event: "room_created", Data: json.RawMessage(`{"code":"AB12"}`)})
+	log.Append(Entry{Time: at, From: "p1", Event: "validate_answer"})
+	log.Append(Entry{Time: at, To: "p2", Event: "game_result", Data: json.RawMessage(`{"score":3}`)})
+	log.Append(Entry{Event: "all_players_finished"})
+	log.Close()
+	// Entries after Close are dropped
+	if err := log.Append(Entry{Event: "late"}); err != nil {
+		t.Errorf("Append after Close: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "AB12-1.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	want := []string{
+		`{"time":"2024-01-01T12:00:00Z","event":"room_created","data":{"code":"AB12"}}`,
+		`{"time":"2024-01-01T12:00:00Z","from":"p1","event":"validate_answer"}`,
+		`{"time":"2024-01-01T12:00:00Z","to":"p2","event":"game_result","data":{"score":3}}`,
+	}
+	if len(lines) != 4 {
+		t.Fatalf("log has %d lines, want 4:\n%s", len(lines), data)
+	}
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("line %d = %s, want %s", i+1, lines[i], line)
+		}
+	}
+
+	entries, err := Read(dir, "AB12-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[2].To != "p2" || entries[3].Time.IsZero() {
+		t.Errorf("Read = %+v", entries)
+	}
+
+	// Opening the log again appends to it
+	log, _ = Open(dir, "AB12-1")
+	log.Append(Entry{Event: "reopened"})
+	log.Close()
+	if entries, _ := Read(dir, "AB12-1"); len(entries) != 5 || entries[4].Event != "reopened" {
+		t.Errorf("after reopening, Read = %+v", entries)
+	}
+
+	var nilLog *Log
+	if err := nilLog.Append(Entry{Event: "dropped"}); err != nil || nilLog.Close() != nil {
+		t.Error("nil Log does not drop its entries")
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	log, err := Open(dir, "AB12-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	log.MaxSize = 150
+
+	at := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	var errs []error
+	for i := 0; i < 5; i++ {
+		errs = append(errs, log.Append(Entry{Time: at, Event: "tick"}))
+	}
+	// Each entry takes 47 bytes with its newline, so 3 fit
+	if errs[0] != nil || errs[2] != nil || errs[3] != ErrFull || errs[4] != nil {
+		t.Errorf("Append errors %v, want ErrFull once, for the 4th entry", errs)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, "AB12-1.jsonl")); info.Size() > log.MaxSize {
+		t.Errorf("log of %d bytes, over its MaxSize", info.Size())
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"time":"2024-01-01T12:00:00Z","event":"one"}
+{"time":"2024-01-01T12:00:01Z","event":"two"}
+{"time":"2024-01-01T12:00:02Z","ev`
+	if err := os.WriteFile(filepath.Join(dir, "AB12-1.jsonl"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Read(dir, "AB12-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Event != "two" {
+		t.Errorf("Read = %+v, want the 2 complete entries", entries)
+	}
+
+	if _, err := Read(dir, "AB12-2"); err != ErrNoLog {
+		t.Errorf("Read of a missing log: %v, want ErrNoLog", err)
+	}
+	if _, err := Read(dir, "../AB12-1"); err != ErrInvalidID {
+		t.Errorf("Read of an invalid ID: %v, want ErrInvalidID", err)
+	}
+}
+
+// writeLog writes a log of size bytes last written at modified.
+func writeLog(t *testing.T, dir, id string, size int, modified time.Time) {
+	t.Helper()
+	file := filepath.Join(dir, id+ext)
+	if err := os.WriteFile(file, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeLog(t, dir, "AAAA-1000", 10, now)
+	writeLog(t, dir, "BBBB-3000", 20, now)
+	writeLog(t, dir, "CCCC-2000", 30, now)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "bad id.jsonl"), nil, 0o644)
+
+	games, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, g := range games {
+		ids = append(ids, g.ID)
+	}
+	if strings.Join(ids, ",") != "BBBB-3000,CCCC-2000,AAAA-1000" {
+		t.Errorf("List = %v, want the logs most recent first", ids)
+	}
+	if games[0].Room != "BBBB" || games[0].Size != 20 || !games[0].CreatedAt.Equal(time.UnixMilli(3000)) {
+		t.Errorf("game = %+v", games[0])
+	}
+
+	if games, err := List(filepath.Join(dir, "missing")); err != nil || len(games) != 0 {
+		t.Errorf("List of a missing dir = %v, %v", games, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	// Logs by creation time, the most recent last, each 100 bytes and
+	// written the given number of days ago
+	logs := []struct {
+		id  string
+		age int
+	}{
+		{"AAAA-1000", 9},
+		{"BBBB-2000", 5},
+		{"CCCC-3000", 3},
+		{"DDDD-4000", 1},
+		{"EEEE-5000", 0},
+	}
+
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxSize int64
+		keep    string // ID kept whatever the limits
+		want    string // IDs left
+	}{
+		{"no limits", 0, 0, "", "AAAA-1000,BBBB-2000,CCCC-3000,DDDD-4000,EEEE-5000"},
+		{"too old", 4 * day, 0, "", "CCCC-3000,DDDD-4000,EEEE-5000"},
+		{"exactly the max age", 3 * day, 0, "", "CCCC-3000,DDDD-4000,EEEE-5000"},
+		{"too big", 0, 250, "", "DDDD-4000,EEEE-5000"},
+		{"exactly the max size", 0, 300, "", "CCCC-3000,DDDD-4000,EEEE-5000"},
+		{"too old and too big", 4 * day, 150, "", "EEEE-5000"},
+		{"kept though old", 2 * day, 0, "AAAA-1000", "AAAA-1000,DDDD-4000,EEEE-5000"},
+		{"kept logs count towards the size", 0, 250, "EEEE-5000", "DDDD-4000,EEEE-5000"},
+		{"kept old log fills the size", 0, 250, "AAAA-1000", "AAAA-1000,DDDD-4000,EEEE-5000"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, l := range logs {
+			writeLog(t, dir, l.id, 100, now.Add(-time.Duration(l.age)*day))
+		}
+
+		removed, err := Prune(dir, now, tt.maxAge, tt.maxSize, func(id string) bool { return id == tt.keep })
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		games, _ := List(dir)
+		var left []string
+		for i := len(games) - 1; i >= 0; i-- {
+			left = append(left, games[i].ID)
+		}
+		if got := strings.Join(left, ","); got != tt.want {
+			t.Errorf("%s: left %s, want %s", tt.name, got, tt.want)
+		}
+		if removed != len(logs)-len(left) {
+			t.Errorf("%s: Prune removed %d, %d logs are gone", tt.name, removed, len(logs)-len(left))
+		}
+	}
+}
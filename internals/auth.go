@@ -0,0 +1,280 @@
+package internals
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/accounts"
+	"github.com/adimail/fun-with-flags/internals/ratelimit"
+)
+
+const (
+	accountsFile  = "./data/accounts.json"
+	sessionCookie = "session"
+)
+
+// authAttemptLimit caps the logins and registrations of each client address
+// per minute, so passwords can't be guessed at the speed of the network.
+const authAttemptLimit = 10
+
+var (
+	accountStore *accounts.Store
+	accountsOnce sync.Once
+
+	authAttempts = ratelimit.New(authAttemptLimit, time.Minute)
+)
+
+// loadAccounts reads the accounts from data/accounts.json on first use and
+// returns the cached store afterwards.
+func loadAccounts() *accounts.Store {
+	accountsOnce.Do(func() {
+		var err error
+		accountStore, err = accounts.Load(accountsFile)
+		if err != nil {
+			slog.Error("Failed to load accounts", "error", err)
+			accountStore = accounts.New()
+		}
+	})
+	return accountStore
+}
+
+// currentAccount returns the account logged in on the request through the
+// session cookie. Accounts are optional: guests get false.
+func currentAccount(r *http.Request) (accounts.Account, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return accounts.Account{}, false
+	}
+	return loadAccounts().Session(cookie.Value)
+}
+
+// resolveUsername returns the name a player goes by in a room. Logged in
+// players always play under their account name, whatever they asked for.
+// Guests pick their own name, as long as it doesn't belong to an account.
+func resolveUsername(r *http.Request, requested string) (string, error) {
+	if account, ok := currentAccount(r); ok {
+		return account.Username, nil
+	}
+	if _, taken := loadAccounts().Lookup(strings.TrimSpace(requested)); taken {
+		return "", errors.New("this username belongs to an account, log in to use it")
+	}
+	return requested, nil
+}
+
+// startSession opens a session for the account and sets its cookie.
+func startSession(w http.ResponseWriter, r *http.Request, account accounts.Account) error {
+	token, expires, err := loadAccounts().NewSession(account)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// credentials is the request body of registerHandler and loginHandler.
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// registerHandler creates an account and logs it in.
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - username: 4-20 letters, digits, "-" or "_", unique regardless of case
+//   - password: 8-72 characters
+//
+// Response:
+//   - 200: {"username"}, with the session cookie set
+//   - 400: Invalid username or password
+//   - 409: Username already taken
+//   - 429: Too many logins or registrations from this address
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowRequest(w, r, authAttempts, clientAddress(r)) {
+		return
+	}
+
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+
+	account, err := loadAccounts().Register(req.Username, req.Password)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, accounts.ErrUsernameTaken):
+			status = http.StatusConflict
+		case !errors.Is(err, accounts.ErrInvalidUsername) && !errors.Is(err, accounts.ErrInvalidPassword):
+			requestLogger(r).Error("Failed to register account", "username", req.Username, "error", err)
+			status = http.StatusInternalServerError
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := startSession(w, r, account); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": account.Username,
+	})
+}
+
+// loginHandler logs an account in.
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - username: The account username, any case
+//   - password: The account password
+//
+// Response:
+//   - 200: {"username"}, with the session cookie set
+//   - 401: Wrong username or password
+//   - 429: Too many logins or registrations from this address
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowRequest(w, r, authAttempts, clientAddress(r)) {
+		return
+	}
+
+	var req credentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+
+	account, err := loadAccounts().Authenticate(req.Username, req.Password)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := startSession(w, r, account); err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username": account.Username,
+	})
+}
+
+// logoutHandler ends the session of the request and clears its cookie.
+//
+// HTTP Method: POST
+//
+// Response:
+//   - 204: Logged out, or was not logged in
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		loadAccounts().EndSession(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// meHandler returns the account logged in on the request.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"username", "created_at", "admin"}
+//   - 401: Not logged in
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := currentAccount(r)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Not logged in"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"username":   account.Username,
+		"created_at": account.CreatedAt.Format(time.RFC3339),
+		"admin":      account.Admin,
+	})
+}
+
+// requireAccount returns the account logged in on the request, or writes a
+// 401 response and returns false for guests.
+func requireAccount(w http.ResponseWriter, r *http.Request) (accounts.Account, bool) {
+	account, ok := currentAccount(r)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Log in to use this feature"})
+	}
+	return account, ok
+}
+
+// clientAddress returns the IP address a request came from, without its
+// port.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowRequest counts a request against the limiter under key, and writes a
+// 429 response with a Retry-After header and returns false once key went
+// over the limit.
+func allowRequest(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, key string) bool {
+	now := time.Now()
+	if limiter.Allow(key, now) {
+		return true
+	}
+
+	retry := limiter.RetryAfter(key, now)
+	requestLogger(r).Warn("Rate limited", "route", routeTemplate(r), "key", key, "retry_after", retry)
+	w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())+1))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: "Too many requests, try again later"})
+	return false
+}
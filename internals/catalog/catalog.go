@@ -0,0 +1,161 @@
+// Package catalog loads the list of countries the game draws its
+// questions from, as stored in data/countries.csv.
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Country is one row of the countries CSV.
+type Country struct {
+	Name    string
+	Code    string // ISO 3166-1 alpha-2, also the name of the flag SVG
+	ISO3    string // ISO 3166-1 alpha-3, the id of the map outline; empty if Code isn't an ISO code
+	Lat     float64
+	Lon     float64
+	Aliases []string // other accepted names, see LoadAliases
+
+	// Trivia columns, empty when the CSV row doesn't have them
+	Capital         string
+	Continent       string
+	Neighbours      []string // ISO alpha-2 codes of bordering countries
+	EurovisionDebut int      // year of the first Eurovision entry, 0 if none
+}
+
+// FlagURL returns the URL the frontend loads the country's flag from.
+func (c Country) FlagURL() string {
+	return FlagURL(c.Code)
+}
+
+// FlagURL returns the URL of the flag SVG for an ISO alpha-2 code.
+func FlagURL(code string) string {
+	return path.Join("/static/svg", code+".svg")
+}
+
+// CodeFromFlagURL extracts the ISO code from a URL built by FlagURL.
+// Any other string is returned unchanged, so callers can pass either
+// a code or a flag URL.
+func CodeFromFlagURL(s string) string {
+	if strings.HasPrefix(s, "/static/svg/") && strings.HasSuffix(s, ".svg") {
+		return strings.TrimSuffix(path.Base(s), ".svg")
+	}
+	return s
+}
+
+// Catalog is the set of countries available to the game.
+type Catalog struct {
+	Countries []Country
+	byCode    map[string]int
+}
+
+// Load reads a countries CSV with the columns name, ISO code, latitude and
+// longitude, optionally followed by capital, continent, neighbours (ISO codes
+// separated by ";") and Eurovision debut year.
+func Load(file string) (*Catalog, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{byCode: make(map[string]int)}
+	for i, row := range rows {
+		if len(row) < 4 {
+			return nil, fmt.Errorf("%s:%d: expected 4 columns, got %d", file, i+1, len(row))
+		}
+
+		lat, err := strconv.ParseFloat(row[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid latitude: %w", file, i+1, err)
+		}
+		lon, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid longitude: %w", file, i+1, err)
+		}
+
+		country := Country{
+			Name: row[0],
+			Code: strings.ToUpper(row[1]),
+			Lat:  lat,
+			Lon:  lon,
+		}
+		country.ISO3, _ = ISO3(country.Code)
+
+		if len(row) > 4 {
+			country.Capital = row[4]
+		}
+		if len(row) > 5 {
+			country.Continent = row[5]
+		}
+		if len(row) > 6 && row[6] != "" {
+			country.Neighbours = strings.Split(strings.ToUpper(row[6]), ";")
+		}
+		if len(row) > 7 && row[7] != "" {
+			country.EurovisionDebut, err = strconv.Atoi(row[7])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid Eurovision debut year: %w", file, i+1, err)
+			}
+		}
+
+		c.byCode[country.Code] = len(c.Countries)
+		c.Countries = append(c.Countries, country)
+	}
+	return c, nil
+}
+
+// LoadAliases reads a CSV of alternative country names with the columns
+// ISO code and alias, e.g. "GB,Great Britain". Aliases of countries that
+// are not in the catalog are ignored.
+func (c *Catalog) LoadAliases(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		if len(row) < 2 {
+			return fmt.Errorf("%s:%d: expected 2 columns, got %d", file, i+1, len(row))
+		}
+		if j, ok := c.byCode[strings.ToUpper(row[0])]; ok {
+			c.Countries[j].Aliases = append(c.Countries[j].Aliases, row[1])
+		}
+	}
+	return nil
+}
+
+// ByCode looks up a country by its ISO alpha-2 code, ignoring case.
+func (c *Catalog) ByCode(code string) (Country, bool) {
+	i, ok := c.byCode[strings.ToUpper(code)]
+	if !ok {
+		return Country{}, false
+	}
+	return c.Countries[i], true
+}
+
+// ByISO3 looks up a country by its ISO alpha-3 code, or the id of its map
+// outline, ignoring case.
+func (c *Catalog) ByISO3(code string) (Country, bool) {
+	alpha2, ok := ISO2(code)
+	if !ok {
+		return Country{}, false
+	}
+	return c.ByCode(alpha2)
+}
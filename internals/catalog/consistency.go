@@ -0,0 +1,61 @@
+package catalog
+
+import "fmt"
+
+// Kinds of Problem
+const (
+	UnknownCode    = "unknown code"    // not an ISO 3166-1 alpha-2 code
+	MissingFlag    = "missing flag"    // no SVG named after the code
+	MissingOutline = "missing polygon" // not on the map
+	NameMismatch   = "name mismatch"   // the map calls the country differently
+)
+
+// Problem is an inconsistency between the catalog and the other sources of
+// country data.
+type Problem struct {
+	Code   string // ISO alpha-2 code of the country
+	Kind   string
+	Detail string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Code, p.Kind, p.Detail)
+}
+
+// Check compares the catalog with the flags and map outlines available.
+// flags holds the names of the flag SVGs without extension; outlines maps the
+// ids of the map outlines (ISO alpha-3, see ISO2) to the country names the
+// map shows. The map highlights answers by name, so names must match exactly.
+func (c *Catalog) Check(flags map[string]bool, outlines map[string]string) []Problem {
+	var problems []Problem
+	for _, country := range c.Countries {
+		if !flags[country.Code] {
+			problems = append(problems, Problem{country.Code, MissingFlag,
+				fmt.Sprintf("no %s.svg for %s", country.Code, country.Name)})
+		}
+
+		if country.ISO3 == "" {
+			problems = append(problems, Problem{country.Code, UnknownCode,
+				fmt.Sprintf("%s is not an ISO 3166-1 alpha-2 code", country.Code)})
+			continue
+		}
+
+		name, ok := outlines[country.ISO3]
+		if !ok {
+			for id, n := range outlines {
+				if alpha2, _ := ISO2(id); alpha2 == country.Code {
+					name, ok = n, true
+				}
+			}
+		}
+		switch {
+		case !ok:
+			problems = append(problems, Problem{country.Code, MissingOutline,
+				fmt.Sprintf("no outline with id %s for %s", country.ISO3, country.Name)})
+		case name != country.Name:
+			problems = append(problems, Problem{country.Code, NameMismatch,
+				fmt.Sprintf("%q in the catalog, %q on the map", country.Name, name)})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MatchResult is the outcome of matching a typed answer against a country.
+type MatchResult struct {
+	Accepted bool
+	NearMiss bool   // accepted within the edit-distance tolerance, not exactly
+	Matched  string // the name or alias the answer was matched to
+	Distance int
+}
+
+// diacritics folds the accented Latin letters found in country names to
+// their ASCII base letter.
+var diacritics = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ğ': "g", 'ģ': "g", 'ķ': "k", 'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ŕ': "r", 'ř': "r", 'ś': "s", 'ş': "s", 'š': "s", 'ș': "s", 'ß': "ss",
+	'ţ': "t", 'ť': "t", 'ț': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// Normalize lowercases a name, folds diacritics, turns punctuation into
+// spaces and drops a leading "the", so that "Côte d'Ivoire" and
+// "cote d ivoire" compare equal.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if folded, ok := diacritics[r]; ok {
+			b.WriteString(folded)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else if r != '.' {
+			b.WriteRune(' ')
+		}
+	}
+
+	normalized := strings.Join(strings.Fields(b.String()), " ")
+	return strings.TrimPrefix(normalized, "the ")
+}
+
+// Match checks a typed answer against the country with the given code. The
+// answer is compared, after Normalize, with the country's name and aliases.
+// When there is no exact match, an answer within tolerance edits of a name
+// is accepted as a near miss. The tolerance shrinks for short names (one
+// edit per 5 letters) and a near miss is refused when the answer exactly
+// names another country, so "Iraq" is never taken for "Iran".
+func (c *Catalog) Match(code, answer string, tolerance int) MatchResult {
+	country, ok := c.ByCode(code)
+	if !ok {
+		return MatchResult{}
+	}
+
+	input := Normalize(answer)
+	if input == "" {
+		return MatchResult{}
+	}
+
+	names := append([]string{country.Name}, country.Aliases...)
+	for _, name := range names {
+		if Normalize(name) == input {
+			return MatchResult{Accepted: true, Matched: name}
+		}
+	}
+
+	if tolerance <= 0 {
+		return MatchResult{}
+	}
+	if other, ok := c.ByName(input); ok && other.Code != country.Code {
+		return MatchResult{}
+	}
+
+	best := MatchResult{Distance: -1}
+	for _, name := range names {
+		target := Normalize(name)
+		allowed := min(tolerance, len(target)/5)
+		distance := editDistance(input, target)
+		if distance <= allowed && (best.Distance < 0 || distance < best.Distance) {
+			best = MatchResult{Accepted: true, NearMiss: true, Matched: name, Distance: distance}
+		}
+	}
+	if !best.Accepted {
+		return MatchResult{}
+	}
+	return best
+}
+
+// ByName looks up a country by its name or one of its aliases, compared
+// after Normalize.
+func (c *Catalog) ByName(name string) (Country, bool) {
+	input := Normalize(name)
+	for _, country := range c.Countries {
+		if Normalize(country.Name) == input {
+			return country, true
+		}
+		for _, alias := range country.Aliases {
+			if Normalize(alias) == input {
+				return country, true
+			}
+		}
+	}
+	return Country{}, false
+}
+
+// editDistance returns the optimal string alignment distance between two
+// strings, counted in runes: insertions, deletions, substitutions and swaps
+// of two adjacent letters ("Spian") each cost one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
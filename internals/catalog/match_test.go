@@ -0,0 +1,141 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testCatalog loads a small catalog with aliases from temporary files.
+func testCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	dir := t.TempDir()
+	countries := filepath.Join(dir, "countries.csv")
+	aliases := filepath.Join(dir, "aliases.csv")
+	if err := os.WriteFile(countries, []byte(`United Kingdom,GB,54,-2
+Netherlands,NL,52.5,5.75
+Spain,ES,40,-4
+Chad,TD,15,19
+Gambia,GM,13.5,-15.5
+Zambia,ZM,-15,30
+Côte d'Ivoire,CI,8,-5
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(aliases, []byte(`GB,UK
+GB,Great Britain
+NL,Holland
+FR,France
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(countries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadAliases(aliases); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestMatch(t *testing.T) {
+	c := testCatalog(t)
+
+	tests := []struct {
+		name      string
+		code      string
+		answer    string
+		tolerance int
+		want      MatchResult
+	}{
+		{"exact name", "GB", "United Kingdom", 0, MatchResult{Accepted: true, Matched: "United Kingdom"}},
+		{"case and spaces", "GB", "  united   KINGDOM ", 0, MatchResult{Accepted: true, Matched: "United Kingdom"}},
+		{"alias", "GB", "uk", 0, MatchResult{Accepted: true, Matched: "UK"}},
+		{"alias with dots", "GB", "U.K.", 0, MatchResult{Accepted: true, Matched: "UK"}},
+		{"longer alias", "GB", "great britain", 0, MatchResult{Accepted: true, Matched: "Great Britain"}},
+		{"leading the", "NL", "The Netherlands", 0, MatchResult{Accepted: true, Matched: "Netherlands"}},
+		{"diacritics", "CI", "cote d ivoire", 0, MatchResult{Accepted: true, Matched: "Côte d'Ivoire"}},
+		{"typo in alias", "NL", "Hollnd", 2, MatchResult{Accepted: true, NearMiss: true, Matched: "Holland", Distance: 1}},
+		{"swapped letters", "ES", "Spian", 2, MatchResult{Accepted: true, NearMiss: true, Matched: "Spain", Distance: 1}},
+		{"typo without tolerance", "ES", "Spian", 0, MatchResult{}},
+		{"short name allows no typo", "TD", "Chda", 3, MatchResult{}},
+		{"short alias allows no typo", "GB", "UL", 3, MatchResult{}},
+		{"typo over the tolerance", "ES", "Spnia", 2, MatchResult{}},
+		{"another country's name", "GM", "Zambia", 2, MatchResult{}},
+		{"typo next to another country", "GM", "Gambla", 2, MatchResult{Accepted: true, NearMiss: true, Matched: "Gambia", Distance: 1}},
+		{"alias of another country", "GB", "Holland", 2, MatchResult{}},
+		{"empty answer", "GB", " . ", 2, MatchResult{}},
+		{"unknown code", "XX", "United Kingdom", 2, MatchResult{}},
+		{"alias outside the catalog ignored", "FR", "France", 0, MatchResult{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.Match(tt.code, tt.answer, tt.tolerance)
+			if got != tt.want {
+				t.Errorf("Match(%q, %q, %d) = %+v, want %+v", tt.code, tt.answer, tt.tolerance, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByName(t *testing.T) {
+	c := testCatalog(t)
+
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"spain", "ES", true},
+		{"Great Britain", "GB", true},
+		{"the netherlands", "NL", true},
+		{"Cote d'Ivoire", "CI", true},
+		{"Spian", "", false},
+	}
+	for _, tt := range tests {
+		country, ok := c.ByName(tt.name)
+		if ok != tt.ok || country.Code != tt.want {
+			t.Errorf("ByName(%q) = %q, %v, want %q, %v", tt.name, country.Code, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Côte d'Ivoire", "cote d ivoire"},
+		{"U.K.", "uk"},
+		{"The Gambia", "gambia"},
+		{"Bosnia-Herzegovina", "bosnia herzegovina"},
+		{"  São   Tomé ", "sao tome"},
+		{"Österreich", "osterreich"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"spain", "spain", 0},
+		{"spian", "spain", 1},
+		{"span", "spain", 1},
+		{"spaiin", "spain", 1},
+		{"iraq", "iran", 1},
+		{"", "chad", 4},
+		{"osterreich", "österreich", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
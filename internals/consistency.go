@@ -0,0 +1,64 @@
+package internals
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/geo"
+)
+
+// countryDataProblems compares data/countries.csv with the flag SVGs and the
+// map outlines of countries.geo.json (see catalog.Catalog.Check). The files
+// are read afresh from the repository at root.
+//
+// Parameters:
+//   - root: The repository root, "." for the server
+//
+// Returns:
+//   - []catalog.Problem: The countries missing a flag or an outline, or named
+//     differently on the map
+//   - error: A file could not be read
+func countryDataProblems(root string) ([]catalog.Problem, error) {
+	countries, err := catalog.Load(filepath.Join(root, countriesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, flagsDir))
+	if err != nil {
+		return nil, err
+	}
+	flags := make(map[string]bool)
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".svg"); ok {
+			flags[name] = true
+		}
+	}
+
+	world, err := geo.Load(filepath.Join(root, worldFile))
+	if err != nil {
+		return nil, err
+	}
+	outlines := make(map[string]string)
+	for _, feature := range world.Features {
+		outlines[feature.ID] = feature.Name
+	}
+
+	return countries.Check(flags, outlines), nil
+}
+
+// reportCountryData logs the inconsistencies found by countryDataProblems.
+// Router runs it at startup.
+func reportCountryData() {
+	problems, err := countryDataProblems(".")
+	if err != nil {
+		slog.Error("Failed to check country data", "error", err)
+		return
+	}
+	for _, problem := range problems {
+		slog.Warn("Country data", "problem", problem)
+	}
+}
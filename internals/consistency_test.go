@@ -0,0 +1,15 @@
+package internals
+
+import "testing"
+
+// TestCountryData fails when a country of data/countries.csv has no flag or
+// map outline, or is named differently on the map.
+func TestCountryData(t *testing.T) {
+	problems, err := countryDataProblems("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
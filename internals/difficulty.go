@@ -0,0 +1,235 @@
+package internals
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/adimail/fun-with-flags/internals/ratelimit"
+	"github.com/adimail/fun-with-flags/internals/stats"
+)
+
+const statsFile = "./data/stats.json"
+
+// answerReportLimit is how many answers a player may report to
+// recordStatsHandler a minute, well above what anyone answers by hand.
+const answerReportLimit = 60
+
+var answerReports = ratelimit.New(answerReportLimit, time.Minute)
+
+var (
+	answerStats *stats.Stats
+	statsOnce   sync.Once
+)
+
+// loadStats reads the answer statistics from data/stats.json on first use
+// and returns the cached statistics afterwards. A file that cannot be read
+// is logged and replaced by empty statistics on the next save.
+func loadStats() *stats.Stats {
+	statsOnce.Do(func() {
+		var err error
+		answerStats, err = stats.Load(statsFile)
+		if err != nil {
+			slog.Error("Failed to load answer statistics", "error", err)
+			answerStats = stats.New()
+		}
+	})
+	return answerStats
+}
+
+// recordAnswerStats adds the outcome of an answer to the statistics of the
+// question's country. Trivia questions (capitals, neighbours...) say nothing
+// about how hard the flag is and are not recorded.
+func recordAnswerStats(question *game.Question, correct bool, elapsed time.Duration) {
+	countAnswer(question.Type, correct)
+	switch question.Type {
+	case "MCQ", "MAP", "REVERSE", "TYPED":
+		loadStats().Record(question.Code, correct, elapsed)
+	}
+}
+
+// validateDifficulty checks the difficulty requested for a game. An empty
+// difficulty picks the countries uniformly at random.
+func validateDifficulty(difficulty string) error {
+	switch difficulty {
+	case "", "easy", "medium", "hard", "adaptive":
+		return nil
+	}
+	return errors.New("difficulty must be easy, medium, hard or adaptive")
+}
+
+// difficultyWeight returns how likely a flag of the given difficulty (see
+// stats.CountryStats.Difficulty) is to be picked for a game of the
+// requested difficulty. Every flag keeps a small chance, so that small
+// catalogs still fill a game.
+func difficultyWeight(difficulty string, score float64) float64 {
+	const floor = 0.02
+	switch difficulty {
+	case "easy":
+		return floor + (1-score)*(1-score)
+	case "medium":
+		return floor + 1 - 2*math.Abs(score-0.5)
+	case "hard":
+		return floor + score*score
+	}
+	return 1
+}
+
+// selectCountriesByDifficulty draws count countries, weighting each by how
+// well its difficulty fits the requested one. "adaptive" draws from every
+// difficulty and orders the questions from the easiest to the hardest, so
+// the game gets harder as it goes on. An empty difficulty draws uniformly.
+func selectCountriesByDifficulty(countries []catalog.Country, count int, difficulty string, rng *rand.Rand) []catalog.Country {
+	if difficulty == "" {
+		return selectRandomCountries(countries, count, rng)
+	}
+
+	answers := loadStats()
+	scores := make(map[string]float64, len(countries))
+	for _, country := range countries {
+		scores[country.Code] = answers.Difficulty(country.Code)
+	}
+
+	// Weighted sampling without replacement: each country gets the key
+	// u^(1/weight) and the count highest keys are kept.
+	type candidate struct {
+		country catalog.Country
+		key     float64
+	}
+	candidates := make([]candidate, len(countries))
+	for i, country := range countries {
+		weight := difficultyWeight(difficulty, scores[country.Code])
+		candidates[i] = candidate{country, math.Pow(rng.Float64(), 1/weight)}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].key > candidates[j].key
+	})
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	selected := make([]catalog.Country, len(candidates))
+	for i, c := range candidates {
+		selected[i] = c.country
+	}
+
+	if difficulty == "adaptive" {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return scores[selected[i].Code] < scores[selected[j].Code]
+		})
+	}
+	return selected
+}
+
+// statsHandler lists the answer statistics and difficulty of every country.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: Array of {"code", "name", "answers", "percent_correct", "median_ms",
+//     "difficulty"}, hardest first
+//   - 500: The catalog could not be loaded
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	answers := loadStats()
+	list := []map[string]interface{}{}
+	for _, country := range countries.Countries {
+		c := answers.Country(country.Code)
+		list = append(list, map[string]interface{}{
+			"code":            country.Code,
+			"name":            country.Name,
+			"answers":         c.Answers,
+			"percent_correct": c.PercentCorrect(),
+			"median_ms":       c.MedianTime().Milliseconds(),
+			"difficulty":      c.Difficulty(),
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i]["difficulty"].(float64) > list[j]["difficulty"].(float64)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// recordStatsHandler records the outcome of a single player answer. Single
+// player games check most answers in the browser, which reports them here.
+// The server can't tell whether such a report is true, so only logged in
+// players may send them, up to answerReportLimit a minute.
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - type: Question type, as in the question ("MCQ", "MAP", ...)
+//   - code: ISO code of the country asked about
+//   - correct: Whether the answer was right
+//   - elapsed_ms: Time taken to answer, in milliseconds
+//
+// Response:
+//   - 204: Answer recorded
+//   - 400: Invalid request parameters
+//   - 401: Not logged in
+//   - 404: Unknown country code
+//   - 429: Too many answers reported
+func recordStatsHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+	if !allowRequest(w, r, answerReports, account.Username) {
+		return
+	}
+
+	var req struct {
+		Type      string `json:"type"`
+		Code      string `json:"code"`
+		Correct   bool   `json:"correct"`
+		ElapsedMs int64  `json:"elapsed_ms"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+
+	if req.ElapsedMs < 0 || req.ElapsedMs > time.Hour.Milliseconds() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid answer time"})
+		return
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	country, ok := countries.ByCode(req.Code)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Country not found"})
+		return
+	}
+
+	question := &game.Question{Type: req.Type, Code: country.Code}
+	recordAnswerStats(question, req.Correct, time.Duration(req.ElapsedMs)*time.Millisecond)
+	w.WriteHeader(http.StatusNoContent)
+}
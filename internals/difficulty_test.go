@@ -0,0 +1,102 @@
+package internals
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/stats"
+)
+
+// useStats makes loadStats return s until the test ends.
+func useStats(t *testing.T, s *stats.Stats) {
+	statsOnce.Do(func() {})
+	saved := answerStats
+	answerStats = s
+	t.Cleanup(func() { answerStats = saved })
+}
+
+func TestDifficultyWeight(t *testing.T) {
+	for _, difficulty := range []string{"easy", "medium", "hard"} {
+		for _, score := range []float64{0, 0.25, 0.5, 0.75, 1} {
+			if w := difficultyWeight(difficulty, score); w <= 0 {
+				t.Errorf("difficultyWeight(%q, %v) = %v, every flag must keep a chance", difficulty, score, w)
+			}
+		}
+	}
+
+	if difficultyWeight("easy", 0.1) <= difficultyWeight("easy", 0.9) {
+		t.Error("easy games weigh hard flags more than easy ones")
+	}
+	if difficultyWeight("hard", 0.9) <= difficultyWeight("hard", 0.1) {
+		t.Error("hard games weigh easy flags more than hard ones")
+	}
+	if difficultyWeight("medium", 0.5) <= difficultyWeight("medium", 0.1) || difficultyWeight("medium", 0.5) <= difficultyWeight("medium", 0.9) {
+		t.Error("medium games don't prefer flags of medium difficulty")
+	}
+	if difficultyWeight("adaptive", 0.1) != difficultyWeight("adaptive", 0.9) {
+		t.Error("adaptive games weigh flags by difficulty")
+	}
+}
+
+func TestSelectCountriesByDifficulty(t *testing.T) {
+	// E0-E9 are always answered right away, H0-H9 always missed
+	answers := stats.New()
+	var countries []catalog.Country
+	for i := 0; i < 10; i++ {
+		easy, hard := "E"+strconv.Itoa(i), "H"+strconv.Itoa(i)
+		countries = append(countries, catalog.Country{Code: easy}, catalog.Country{Code: hard})
+		for j := 0; j < 30; j++ {
+			answers.Record(easy, true, time.Second)
+			answers.Record(hard, false, 20*time.Second)
+		}
+	}
+	useStats(t, answers)
+	rng := rand.New(rand.NewSource(1))
+
+	hardShare := func(difficulty string) float64 {
+		hard, total := 0, 0
+		for i := 0; i < 200; i++ {
+			selected := selectCountriesByDifficulty(countries, 5, difficulty, rng)
+			if len(selected) != 5 {
+				t.Fatalf("%s: selected %d countries, want 5", difficulty, len(selected))
+			}
+			seen := make(map[string]bool)
+			for _, country := range selected {
+				if seen[country.Code] {
+					t.Fatalf("%s: %s selected twice", difficulty, country.Code)
+				}
+				seen[country.Code] = true
+				if country.Code[0] == 'H' {
+					hard++
+				}
+				total++
+			}
+		}
+		return float64(hard) / float64(total)
+	}
+
+	if share := hardShare("easy"); share > 0.2 {
+		t.Errorf("easy games got %.0f%% hard flags", 100*share)
+	}
+	if share := hardShare("hard"); share < 0.8 {
+		t.Errorf("hard games got %.0f%% hard flags", 100*share)
+	}
+	if share := hardShare(""); share < 0.3 || share > 0.7 {
+		t.Errorf("games without a difficulty got %.0f%% hard flags", 100*share)
+	}
+
+	// Adaptive games go from the easiest flags to the hardest
+	selected := selectCountriesByDifficulty(countries, 20, "adaptive", rng)
+	for i := 1; i < len(selected); i++ {
+		if answers.Difficulty(selected[i].Code) < answers.Difficulty(selected[i-1].Code) {
+			t.Fatalf("adaptive order %v is not from easy to hard", selected)
+		}
+	}
+
+	if selected := selectCountriesByDifficulty(countries[:3], 5, "hard", rng); len(selected) != 3 {
+		t.Errorf("selected %d of 3 countries, want all 3", len(selected))
+	}
+}
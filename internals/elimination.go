@@ -0,0 +1,180 @@
+package internals
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+const (
+	defaultLives                = 1
+	defaultEliminationsPerRound = 1
+)
+
+// validateEliminationRules fills in the defaults for an "ELIMINATION" room and
+// checks the rules are within range. Elimination is played in synchronized
+// rounds, so the request is switched to synchronized as well.
+//
+// Validates:
+//   - Lives per player (1-5, defaults to 1)
+//   - Eliminations per round (1-8, defaults to 1)
+func validateEliminationRules(req *game.CreateRoomRequest) error {
+	req.Synchronized = true
+
+	if req.Lives == 0 {
+		req.Lives = defaultLives
+	}
+	if req.Lives < 1 || req.Lives > 5 {
+		return errors.New("lives must be between 1 and 5")
+	}
+
+	if req.EliminationsPerRound == 0 {
+		req.EliminationsPerRound = defaultEliminationsPerRound
+	}
+	if req.EliminationsPerRound < 1 || req.EliminationsPerRound > 8 {
+		return errors.New("eliminations per round must be between 1 and 8")
+	}
+	return nil
+}
+
+// applyEliminations takes lives away at the end of a round of an elimination
+// room. Every player who answered wrong or did not answer loses a life. When
+// fewer than room.EliminationsPerRound players lost one that way, the slowest
+// correct answerers lose a life too until the quota is met. A round never
+// knocks out every remaining player: if it would, nobody loses a life.
+//
+// Players who run out of lives are moved from room.Players to
+// room.Spectators, so they keep following the game without playing.
+//
+// Parameters:
+//   - room: Pointer to the Room instance, GameMode must be "ELIMINATION"
+//   - round: The round that just closed
+//
+// Returns:
+//   - bool: true once one player or fewer is left standing
+//
+// Events broadcast to the room:
+//   - "life_lost": A player lost a life, with the reason and remaining lives
+//   - "player_eliminated": A player ran out of lives
+func applyEliminations(room *game.Room, round *game.Round) bool {
+	type loss struct {
+		player *game.Player
+		reason string
+	}
+
+	mu.Lock()
+
+	var losses []loss
+	var correct []*game.Player
+	for _, player := range room.Players {
+		answer, answered := round.Answers[player.ID]
+		switch {
+		case !answered:
+			losses = append(losses, loss{player, "no_answer"})
+		case !answer.Correct:
+			losses = append(losses, loss{player, "wrong"})
+		default:
+			correct = append(correct, player)
+		}
+	}
+
+	sort.Slice(correct, func(i, j int) bool {
+		return round.Answers[correct[i].ID].Elapsed > round.Answers[correct[j].ID].Elapsed
+	})
+	for _, player := range correct {
+		if len(losses) >= room.EliminationsPerRound {
+			break
+		}
+		losses = append(losses, loss{player, "slowest"})
+	}
+
+	knockedOut := 0
+	for _, l := range losses {
+		if l.player.Lives <= 1 {
+			knockedOut++
+		}
+	}
+	if knockedOut == len(room.Players) {
+		losses = nil
+	}
+
+	var events []map[string]interface{}
+	for _, l := range losses {
+		l.player.Lives--
+		events = append(events, map[string]interface{}{
+			"event": "life_lost",
+			"data": map[string]interface{}{
+				"id":       l.player.ID,
+				"username": l.player.Username,
+				"reason":   l.reason,
+				"lives":    l.player.Lives,
+			},
+		})
+
+		if l.player.Lives == 0 {
+			l.player.Eliminated = true
+			delete(room.Players, l.player.Conn)
+			room.Spectators[l.player.Conn] = l.player
+			events = append(events, map[string]interface{}{
+				"event": "player_eliminated",
+				"data": map[string]interface{}{
+					"id":       l.player.ID,
+					"username": l.player.Username,
+					"reason":   l.reason,
+				},
+			})
+		}
+	}
+
+	remaining := len(room.Players)
+	mu.Unlock()
+
+	for _, event := range events {
+		broadcastToRoom(room, event)
+	}
+
+	return remaining <= 1
+}
+
+// eliminationWinner returns the player left standing in an elimination room.
+// If the questions ran out with several players still in, the one with the
+// highest score wins, then the one who spent the least time answering, and
+// the first username in alphabetical order if that is a tie too, so the
+// winner never depends on map order. It returns nil when nobody is left.
+// The caller must hold mu.
+func eliminationWinner(room *game.Room) *game.Player {
+	var winner *game.Player
+	for _, player := range room.Players {
+		if winner == nil || beatsInElimination(player, winner) {
+			winner = player
+		}
+	}
+	return winner
+}
+
+// beatsInElimination reports whether player a ranks before player b at the
+// end of an elimination room, see eliminationWinner.
+func beatsInElimination(a, b *game.Player) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if timeA, timeB := answeringTime(a), answeringTime(b); timeA != timeB {
+		return timeA < timeB
+	}
+	if a.Username != b.Username {
+		return a.Username < b.Username
+	}
+	return a.ID < b.ID
+}
+
+// answeringTime returns the total time a player took over the questions they
+// answered.
+func answeringTime(player *game.Player) time.Duration {
+	var total time.Duration
+	for _, answer := range player.Answers {
+		total += answer.Elapsed
+	}
+	return total
+}
@@ -0,0 +1,50 @@
+package internals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestEliminationWinner(t *testing.T) {
+	player := func(name string, score int, ms ...int) *game.Player {
+		p := &game.Player{ID: name, Username: name, Score: score}
+		for i, elapsed := range ms {
+			p.Answers = append(p.Answers, game.AnswerRecord{QuestionIndex: i, Correct: true, Elapsed: time.Duration(elapsed) * time.Millisecond})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		players []*game.Player
+		want    string // empty for no winner
+	}{
+		{"nobody left", nil, ""},
+		{"last standing", []*game.Player{player("ana", 0)}, "ana"},
+		{"best score", []*game.Player{player("ana", 3, 900), player("bo", 5, 2000), player("cy", 4, 100)}, "bo"},
+		{"same score, faster", []*game.Player{player("ana", 5, 900, 900), player("bo", 5, 500, 600), player("cy", 5, 1000)}, "cy"},
+		{"same score and time", []*game.Player{player("cy", 5, 700), player("ana", 5, 700), player("bo", 5, 300, 400)}, "ana"},
+	}
+	for _, tt := range tests {
+		room := &game.Room{Players: make(map[*websocket.Conn]*game.Player)}
+		for _, p := range tt.players {
+			room.Players[&websocket.Conn{}] = p
+		}
+
+		// Map order changes between runs, the winner must not
+		for i := 0; i < 20; i++ {
+			winner := eliminationWinner(room)
+			got := ""
+			if winner != nil {
+				got = winner.Username
+			}
+			if got != tt.want {
+				t.Errorf("%s: eliminationWinner = %q, want %q", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package internals
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adimail/fun-with-flags/internals/flagsvg"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/mux"
+)
+
+// flagsDir holds the flag SVGs, named by ISO alpha-2 code.
+const flagsDir = "./frontend/static/svg"
+
+// plainFlags is the flag variant asking for the plain flags whatever the
+// difficulty of the game.
+const plainFlags = "plain"
+
+// validateFlagVariant checks the flag variant requested for a room. An empty
+// variant is left to the difficulty (see resolveFlagVariant), "plain" shows
+// the plain flags; otherwise it must be one of flagsvg.Variants.
+func validateFlagVariant(variant string) error {
+	if variant == "" || variant == plainFlags || flagsvg.IsVariant(variant) {
+		return nil
+	}
+	return errors.New("flag variant must be plain or one of " + strings.Join(flagsvg.Variants, ", "))
+}
+
+// resolveFlagVariant returns the flag variant a game is played with, empty
+// for the plain flags. A variant left empty follows the difficulty: hard
+// games show grayscale flags, as colours give most flags away, and the
+// other difficulties the plain flags.
+func resolveFlagVariant(variant, difficulty string) string {
+	switch {
+	case variant == plainFlags:
+		return ""
+	case variant == "" && difficulty == "hard":
+		return "grayscale"
+	}
+	return variant
+}
+
+// applyFlagVariant points the flags of the questions to the given variant,
+// served by flagHandler. Cropped flags each show a random fragment. Questions
+// without a flag (reverse questions show four of them as options) are left
+// as they are.
+func applyFlagVariant(questions []game.Question, variant string) {
+	if variant == "" {
+		return
+	}
+
+	rng := newRandomGenerator()
+	for i := range questions {
+		if questions[i].FlagURL != "" {
+			questions[i].FlagURL = flagsvg.URL(questions[i].Code, variant, rng.Intn(flagsvg.Parts))
+		}
+	}
+}
+
+// flagCode matches the names of the flag SVGs: ISO alpha-2 codes and a few
+// subdivisions such as "gb-eng".
+var flagCode = regexp.MustCompile(`^[A-Za-z]{2}(-[A-Za-z]{2,3})?$`)
+
+// flagExists reports whether there is a flag SVG for code.
+func flagExists(code string) bool {
+	if !flagCode.MatchString(code) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(flagsDir, code+".svg"))
+	return err == nil
+}
+
+// flagHandler serves a flag rewritten into one of the harder variants of the
+// flagsvg package.
+//
+// HTTP Method: GET
+// URL Parameters:
+//   - code: ISO alpha-2 code of the country, as in the flag file names
+//
+// Query Parameters:
+//   - variant: "crop", "grayscale", "mirror" or "blur"
+//   - part: Fragment shown by "crop" (0-4)
+//   - step: Step of the "blur" progression, from 0 (most blurred) to 4 (plain)
+//
+// Response:
+//   - 200: The SVG image
+//   - 400: Invalid variant, part or step
+//   - 404: Unknown country code
+//   - 500: The flag could not be rewritten
+func flagHandler(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	if !flagCode.MatchString(code) {
+		http.Error(w, "Invalid country code", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := flagsvg.Options{Variant: query.Get("variant")}
+	if !flagsvg.IsVariant(opts.Variant) {
+		http.Error(w, "Invalid flag variant", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if part := query.Get("part"); part != "" {
+		opts.Part, err = strconv.Atoi(part)
+		if err != nil || opts.Part < 0 || opts.Part >= flagsvg.Parts {
+			http.Error(w, "Invalid flag part", http.StatusBadRequest)
+			return
+		}
+	}
+	if step := query.Get("step"); step != "" {
+		opts.Step, err = strconv.Atoi(step)
+		if err != nil || opts.Step < 0 || opts.Step >= flagsvg.BlurSteps {
+			http.Error(w, "Invalid blur step", http.StatusBadRequest)
+			return
+		}
+	}
+
+	src, err := os.ReadFile(filepath.Join(flagsDir, code+".svg"))
+	if err != nil {
+		http.Error(w, "Flag not found", http.StatusNotFound)
+		return
+	}
+
+	svg, err := flagsvg.Render(src, opts)
+	if err != nil {
+		http.Error(w, "Failed to render flag: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(svg)
+}
@@ -0,0 +1,23 @@
+package internals
+
+import "testing"
+
+func TestResolveFlagVariant(t *testing.T) {
+	tests := []struct {
+		variant, difficulty, want string
+	}{
+		{"", "", ""},
+		{"", "easy", ""},
+		{"", "adaptive", ""},
+		{"", "hard", "grayscale"},
+		{"plain", "hard", ""},
+		{"plain", "", ""},
+		{"mirror", "hard", "mirror"},
+		{"blur", "easy", "blur"},
+	}
+	for _, tt := range tests {
+		if got := resolveFlagVariant(tt.variant, tt.difficulty); got != tt.want {
+			t.Errorf("resolveFlagVariant(%q, %q) = %q, want %q", tt.variant, tt.difficulty, got, tt.want)
+		}
+	}
+}
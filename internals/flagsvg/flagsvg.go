@@ -0,0 +1,218 @@
+// Package flagsvg produces harder variants of the flag SVGs in
+// frontend/static/svg by rewriting the SVG source: a cropped fragment, a
+// grayscale or mirrored flag, and a blur that is revealed step by step.
+package flagsvg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Variants lists the flag variants Render understands.
+var Variants = []string{"crop", "grayscale", "mirror", "blur"}
+
+const (
+	// Parts is the number of fragments a flag can be cropped to: the four
+	// quarters and the centre.
+	Parts = 5
+
+	// BlurSteps is the number of steps of the blur progression. Step 0 is
+	// the most blurred and step BlurSteps-1 the plain flag.
+	BlurSteps = 5
+)
+
+// blurAmounts is the blur radius of each step, as a fraction of the flag width.
+var blurAmounts = [BlurSteps]float64{0.08, 0.05, 0.03, 0.015, 0}
+
+// Options selects the variant to render.
+type Options struct {
+	Variant string
+	Part    int // fragment shown by "crop", 0 to Parts-1
+	Step    int // step of the "blur" progression, 0 to BlurSteps-1
+}
+
+// IsVariant reports whether name is one of Variants.
+func IsVariant(name string) bool {
+	for _, variant := range Variants {
+		if variant == name {
+			return true
+		}
+	}
+	return false
+}
+
+// URL returns the URL a variant of a country's flag is served from. The part
+// is only used by the "crop" variant.
+func URL(code, variant string, part int) string {
+	query := url.Values{"variant": {variant}}
+	if variant == "crop" {
+		query.Set("part", strconv.Itoa(part))
+	}
+	return "/api/flag/" + code + "?" + query.Encode()
+}
+
+// box is the viewBox of an SVG document.
+type box struct {
+	x, y, w, h float64
+}
+
+func (b box) String() string {
+	return fmt.Sprintf("%g %g %g %g", b.x, b.y, b.w, b.h)
+}
+
+var sizeAttr = regexp.MustCompile(`\s(width|height|viewBox)\s*=\s*("[^"]*"|'[^']*')`)
+
+// Render rewrites the SVG document src into the variant selected by opts.
+//
+// The root <svg> element loses its width and height so the result scales to
+// whatever box displays it, and gets a viewBox (the cropped one for "crop").
+// Its content is wrapped in a group carrying the filter or transform of the
+// variant.
+func Render(src []byte, opts Options) ([]byte, error) {
+	if !IsVariant(opts.Variant) {
+		return nil, fmt.Errorf("unknown flag variant %q", opts.Variant)
+	}
+
+	start, end, view, err := rootElement(src)
+	if err != nil {
+		return nil, err
+	}
+
+	closing := bytes.LastIndex(src, []byte("</svg>"))
+	if closing < end {
+		return nil, errors.New("svg root element is not closed")
+	}
+
+	// Root start tag without its size attributes
+	tag := string(src[start:end])
+	tag = sizeAttr.ReplaceAllString(tag, "")
+	tag = strings.TrimSuffix(strings.TrimSpace(strings.TrimSuffix(tag, ">")), "/")
+
+	var defs, group string
+	switch opts.Variant {
+	case "crop":
+		view = cropBox(view, opts.Part)
+	case "grayscale":
+		defs = `<filter id="fwf-variant"><feColorMatrix type="saturate" values="0"/></filter>`
+		group = `filter="url(#fwf-variant)"`
+	case "mirror":
+		group = fmt.Sprintf(`transform="translate(%g 0) scale(-1 1)"`, 2*view.x+view.w)
+	case "blur":
+		step := opts.Step
+		if step < 0 {
+			step = 0
+		}
+		if step >= BlurSteps {
+			step = BlurSteps - 1
+		}
+		if radius := blurAmounts[step] * view.w; radius > 0 {
+			defs = fmt.Sprintf(`<filter id="fwf-variant"><feGaussianBlur stdDeviation="%g"/></filter>`, radius)
+			group = `filter="url(#fwf-variant)"`
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `%s viewBox="%s">`, tag, view)
+	if defs != "" {
+		fmt.Fprintf(&out, "<defs>%s</defs>", defs)
+	}
+	fmt.Fprintf(&out, "<g %s>", group)
+	out.Write(src[end:closing])
+	out.WriteString("</g></svg>\n")
+	return out.Bytes(), nil
+}
+
+// cropBox returns the fragment of view shown for part: half the width and
+// half the height of the flag, taken from one of its corners or its centre.
+func cropBox(view box, part int) box {
+	w, h := view.w/2, view.h/2
+	switch ((part % Parts) + Parts) % Parts {
+	case 0:
+		return box{view.x, view.y, w, h}
+	case 1:
+		return box{view.x + w, view.y, w, h}
+	case 2:
+		return box{view.x, view.y + h, w, h}
+	case 3:
+		return box{view.x + w, view.y + h, w, h}
+	default:
+		return box{view.x + w/2, view.y + h/2, w, h}
+	}
+}
+
+// rootElement finds the root <svg> start tag of src and returns its byte
+// range and the area it draws, taken from the viewBox or, failing that, the
+// width and height attributes.
+func rootElement(src []byte) (start, end int, view box, err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(src))
+	for {
+		start = int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return 0, 0, box{}, errors.New("no svg element found")
+		}
+		if err != nil {
+			return 0, 0, box{}, err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if element.Name.Local != "svg" {
+			return 0, 0, box{}, errors.New("root element is not svg")
+		}
+		end = int(decoder.InputOffset())
+
+		var width, height, viewBox string
+		for _, attr := range element.Attr {
+			switch attr.Name.Local {
+			case "width":
+				width = attr.Value
+			case "height":
+				height = attr.Value
+			case "viewBox":
+				viewBox = attr.Value
+			}
+		}
+
+		view, err = parseBox(viewBox, width, height)
+		return start, end, view, err
+	}
+}
+
+// parseBox reads a viewBox attribute, or builds one from the width and
+// height when the attribute is missing.
+func parseBox(viewBox, width, height string) (box, error) {
+	if viewBox != "" {
+		fields := strings.FieldsFunc(viewBox, func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+		if len(fields) != 4 {
+			return box{}, fmt.Errorf("invalid viewBox %q", viewBox)
+		}
+		var values [4]float64
+		for i, field := range fields {
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return box{}, fmt.Errorf("invalid viewBox %q", viewBox)
+			}
+			values[i] = value
+		}
+		return box{values[0], values[1], values[2], values[3]}, nil
+	}
+
+	w, errW := strconv.ParseFloat(strings.TrimSuffix(width, "px"), 64)
+	h, errH := strconv.ParseFloat(strings.TrimSuffix(height, "px"), 64)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return box{}, errors.New("svg has neither a viewBox nor a numeric size")
+	}
+	return box{0, 0, w, h}, nil
+}
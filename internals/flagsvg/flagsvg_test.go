@@ -0,0 +1,126 @@
+package flagsvg
+
+import (
+	"strings"
+	"testing"
+)
+
+// fixture is a small three band flag with a size but no viewBox.
+const fixture = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="900" height="600">
+<rect width="300" height="600" fill="#002654"/>
+<rect x="300" width="300" height="600" fill="#fff"/>
+<rect x="600" width="300" height="600" fill="#ce1126"/>
+</svg>
+`
+
+func TestParseBox(t *testing.T) {
+	tests := []struct {
+		viewBox, width, height string
+		want                   box
+		wantErr                bool
+	}{
+		{"0 0 900 600", "", "", box{0, 0, 900, 600}, false},
+		{"-5,10, 20,30", "", "", box{-5, 10, 20, 30}, false},
+		{"0 0 900 600", "10", "10", box{0, 0, 900, 600}, false},
+		{"", "640px", "480", box{0, 0, 640, 480}, false},
+		{"0 0 900", "", "", box{}, true},
+		{"0 0 wide 600", "", "", box{}, true},
+		{"", "100%", "50", box{}, true},
+		{"", "0", "50", box{}, true},
+		{"", "", "", box{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseBox(tt.viewBox, tt.width, tt.height)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseBox(%q, %q, %q) = %v, %v, want %v, error %v",
+				tt.viewBox, tt.width, tt.height, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCropBox(t *testing.T) {
+	view := box{10, 20, 900, 600}
+	tests := []struct {
+		part int
+		want box
+	}{
+		{0, box{10, 20, 450, 300}},
+		{1, box{460, 20, 450, 300}},
+		{2, box{10, 320, 450, 300}},
+		{3, box{460, 320, 450, 300}},
+		{4, box{235, 170, 450, 300}},
+		{5, box{10, 20, 450, 300}},
+		{-1, box{235, 170, 450, 300}},
+	}
+	for _, tt := range tests {
+		if got := cropBox(view, tt.part); got != tt.want {
+			t.Errorf("cropBox(%v, %d) = %v, want %v", view, tt.part, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		contains []string
+		excludes []string
+	}{
+		{"crop", Options{Variant: "crop", Part: 3},
+			[]string{`viewBox="450 300 450 300"`, `<g >`}, []string{"filter"}},
+		{"grayscale", Options{Variant: "grayscale"},
+			[]string{`viewBox="0 0 900 600"`, `<feColorMatrix type="saturate" values="0"/>`, `<g filter="url(#fwf-variant)">`}, nil},
+		{"mirror", Options{Variant: "mirror"},
+			[]string{`<g transform="translate(900 0) scale(-1 1)">`}, []string{"filter"}},
+		{"blur first step", Options{Variant: "blur", Step: 0},
+			[]string{`<feGaussianBlur stdDeviation="72"/>`}, nil},
+		{"blur step below range", Options{Variant: "blur", Step: -3},
+			[]string{`stdDeviation="72"`}, nil},
+		{"blur last step", Options{Variant: "blur", Step: BlurSteps - 1},
+			nil, []string{"filter"}},
+		{"blur step above range", Options{Variant: "blur", Step: 99},
+			nil, []string{"filter"}},
+	}
+	for _, tt := range tests {
+		out, err := Render([]byte(fixture), tt.opts)
+		if err != nil {
+			t.Errorf("%s: Render error: %v", tt.name, err)
+			continue
+		}
+		svg := string(out)
+		// The root element keeps its namespace but not its size
+		if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="`) {
+			t.Errorf("%s: output root is not sized by its viewBox:\n%s", tt.name, svg)
+		}
+		for _, want := range append(tt.contains, `fill="#ce1126"`, "</g></svg>") {
+			if !strings.Contains(svg, want) {
+				t.Errorf("%s: output lacks %s:\n%s", tt.name, want, svg)
+			}
+		}
+		for _, unwanted := range tt.excludes {
+			if strings.Contains(svg, unwanted) {
+				t.Errorf("%s: output has %s:\n%s", tt.name, unwanted, svg)
+			}
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts Options
+	}{
+		{"unknown variant", fixture, Options{Variant: "sepia"}},
+		{"not svg", `<html><body/></html>`, Options{Variant: "mirror"}},
+		{"no svg element", `<?xml version="1.0"?>`, Options{Variant: "mirror"}},
+		{"not closed", `<svg viewBox="0 0 3 2"><rect/>`, Options{Variant: "mirror"}},
+		{"no size", `<svg><rect/></svg>`, Options{Variant: "mirror"}},
+	}
+	for _, tt := range tests {
+		if _, err := Render([]byte(tt.src), tt.opts); err == nil {
+			t.Errorf("%s: Render succeeded", tt.name)
+		}
+	}
+}
@@ -2,14 +2,20 @@ package game
 
 import (
 	"sync"
+	"time"
 
+	"github.com/adimail/fun-with-flags/internals/audit"
 	"github.com/gorilla/websocket"
 )
 
 type Question struct {
-	FlagURL string   `json:"flag_url"`
-	Options []string `json:"options,omitempty"`
-	Answer  string   `json:"answer"`
+	Type    string   `json:"type,omitempty"`
+	Code    string   `json:"code,omitempty"` // ISO code of the country asked about
+	FlagURL string   `json:"flag_url,omitempty"`
+	Country string   `json:"country,omitempty"` // shown instead of a flag in REVERSE questions
+	Prompt  string   `json:"prompt,omitempty"`  // the question asked, for trivia questions
+	Options []string `json:"options,omitempty"` // flag URLs in REVERSE questions
+	Answer  string   `json:"answer"`            // ISO code in REVERSE questions
 }
 
 type Player struct {
@@ -17,7 +23,28 @@ type Player struct {
 	Username  string
 	Score     int
 	Completed bool
+	Team      string // empty when the room has no teams
 	Conn      *websocket.Conn
+	Account   string // username of the logged in account, empty for guests
+
+	// Elimination rooms only
+	Lives      int
+	Eliminated bool
+
+	// Jury finale
+	Answers      []AnswerRecord
+	LastAnswerAt time.Time
+	JuryPoints   int
+
+	// Wrong clicks per map question index, in rooms with MapRetries
+	MapMisses map[int]int
+}
+
+// AnswerRecord is a player's answer to one question, kept for the jury finale.
+type AnswerRecord struct {
+	QuestionIndex int
+	Correct       bool
+	Elapsed       time.Duration // time taken to answer
 }
 
 type GameState struct {
@@ -26,17 +53,100 @@ type GameState struct {
 }
 
 type Room struct {
-	Code      string
-	Hostname  string
-	Players   map[*websocket.Conn]*Player
-	Questions map[string]*Question
-	Start     bool
-	TimeLimit int // in seconds
-	GameMode  string
+	Code       string
+	Hostname   string
+	Players    map[*websocket.Conn]*Player
+	Spectators map[*websocket.Conn]*Player // receive broadcasts, never play
+	Questions  map[string]*Question
+	Start      bool
+	TimeLimit  int // in seconds
+	GameMode   string
+	Teams      []string // team names, empty for a free-for-all room
+	Tolerance  int      // edit distance allowed in "TYPED" answers
+
+	QuestionTypes []string  // question types mixed in the room
+	FlagVariant   string    // harder flag images, see flagsvg.Variants
+	Difficulty    string    // how the countries were picked, see validateDifficulty
+	PackID        string    // custom question pack, empty for the whole catalog
+	Region        string    // play area, empty for the whole world
+	Bounds        []float64 // bounding box of Region: west, south, east, north
+	MapRetries    int       // wrong clicks answered with a hint per map question
+
+	// Synchronized rooms are driven by the server: every player gets
+	// question N at the same time and has RoundTime seconds to answer.
+	Synchronized bool
+	RoundTime    int    // in seconds
+	Round        *Round // nil between rounds
+
+	// Rules of "ELIMINATION" rooms
+	Lives                int
+	EliminationsPerRound int
+
+	StartedAt     time.Time
+	JuryFinale    bool // reveal a Eurovision-style scoreboard at the end
+	FinaleRunning bool
+	FinaleDone    bool
+
+	ResultsRecorded bool // player results saved, see recordGameResults
+
+	Audit *audit.Log // events of the game, see auditMessage
+}
+
+// Round holds the state of the question currently open in a synchronized room.
+type Round struct {
+	Index     int
+	StartedAt time.Time
+	Answers   map[string]*RoundAnswer // keyed by player ID
+	Done      chan struct{}           // closed once every player has answered
+}
+
+type RoundAnswer struct {
+	PlayerID string
+	Username string
+	Answer   string
+	Correct  bool
+	Elapsed  time.Duration
 }
 
 type CreateRoomRequest struct {
-	TimeLimit    int    `json:"timeLimit"`
-	NumQuestions int    `json:"numQuestions"`
-	GameType     string `json:"gameType"`
+	TimeLimit    int      `json:"timeLimit"`
+	NumQuestions int      `json:"numQuestions"`
+	GameType     string   `json:"gameType"`
+	Synchronized bool     `json:"synchronized"`
+	RoundTime    int      `json:"roundTime"` // in seconds, synchronized rooms only
+	Teams        []string `json:"teams"`
+	Tolerance    int      `json:"tolerance"` // edits allowed in "TYPED" answers, 0 for exact
+
+	// Question types mixed in the room, see mixableQuestionTypes.
+	// Empty plays the question type of GameType.
+	QuestionTypes []string `json:"questionTypes"`
+
+	// Flag variant shown instead of the plain flags: "crop", "grayscale",
+	// "mirror" or "blur". "plain" shows the plain flags, and empty leaves
+	// it to the difficulty: grayscale for "hard", plain otherwise.
+	FlagVariant string `json:"flagVariant"`
+
+	// "easy", "medium" or "hard" favour flags by how often players answer
+	// them right and how fast; "adaptive" gets harder as the game goes on.
+	// Empty picks the countries uniformly at random.
+	Difficulty string `json:"difficulty"`
+
+	// ID of a custom question pack to draw the countries from, see
+	// createPackHandler. Empty draws from the whole catalog.
+	PackID string `json:"packId"`
+
+	// Play area the countries are drawn from and the map zooms to, see
+	// regionMembers. Empty plays on the whole world.
+	Region string `json:"region"`
+
+	// Wrong clicks per map question answered with a hot/cold hint instead
+	// of the answer (0-3), "MAP" rooms only. Each retry costs a point of
+	// the MapRetries+1 a first click is worth.
+	MapRetries int `json:"mapRetries"`
+
+	// Rules of "ELIMINATION" rooms
+	Lives                int `json:"lives"`
+	EliminationsPerRound int `json:"eliminationsPerRound"`
+
+	JuryFinale bool `json:"juryFinale"`
 }
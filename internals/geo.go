@@ -0,0 +1,334 @@
+package internals
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/geo"
+)
+
+const worldFile = "./frontend/static/countries.geo.json"
+
+// maxGeoCache caps the encoded maps geoHandler keeps.
+const maxGeoCache = 64
+
+// mapTolerance is the tolerance of the outlines the game map is drawn with
+// (see initializeMap in game.js). Clicks are located on those outlines, the
+// ones the player saw.
+const mapTolerance = 0.05
+
+// geoTolerances are the tolerances geoHandler serves, others are rounded
+// down to one of them so that the cache holds few maps.
+var geoTolerances = []float64{0, 0.01, 0.05, 0.1, 0.5}
+
+var (
+	world     *geo.World
+	worldErr  error
+	worldOnce sync.Once
+
+	mapWorld     *geo.World
+	mapWorldOnce sync.Once
+
+	geoCache   = make(map[string]*geoMap)
+	geoCacheMu sync.Mutex
+)
+
+// geoMap is an encoded response of geoHandler. Both encodings have their
+// own ETag, as they are different bytes.
+type geoMap struct {
+	body        []byte
+	gzipped     []byte
+	etag        string
+	gzippedEtag string
+}
+
+// loadWorld reads the country outlines shown on the map on first use and
+// returns the cached outlines afterwards. Router loads them at startup.
+func loadWorld() (*geo.World, error) {
+	worldOnce.Do(func() {
+		world, worldErr = geo.Load(worldFile)
+	})
+	return world, worldErr
+}
+
+// loadMapWorld returns the outlines drawn on the game map, simplified at
+// mapTolerance.
+func loadMapWorld() (*geo.World, error) {
+	world, err := loadWorld()
+	if err != nil {
+		return nil, err
+	}
+	mapWorldOnce.Do(func() {
+		mapWorld = world.Simplify(mapTolerance)
+	})
+	return mapWorld, nil
+}
+
+// snapTolerance rounds a tolerance down to the closest of geoTolerances.
+func snapTolerance(tolerance float64) float64 {
+	snapped := geoTolerances[0]
+	for _, t := range geoTolerances {
+		if t <= tolerance {
+			snapped = t
+		}
+	}
+	return snapped
+}
+
+// locateAnswer resolves the point a player clicked on the map to their answer
+// to a "MAP" question: the catalog name of the country there, matched by ISO
+// code (see catalog.Catalog.ByISO3), or the name the map shows for countries
+// outside the catalog. Scoring the point rather than a name sent by the
+// client keeps players from answering without finding the country.
+//
+// The point is located on the simplified outlines the map shows, so a click
+// near a border counts for the country the player saw there. Clicks just
+// outside every outline go to the nearest country within mapTolerance.
+//
+// Parameters:
+//   - lon, lat: The clicked point, in degrees
+//
+// Returns:
+//   - string: The country name, compared with the question's answer
+//   - error: The point is invalid or in no country
+func locateAnswer(lon, lat float64) (string, error) {
+	if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return "", errors.New("invalid coordinates")
+	}
+
+	world, err := loadMapWorld()
+	if err != nil {
+		return "", err
+	}
+
+	feature, ok := world.Locate(lon, lat)
+	if !ok {
+		feature, ok = world.Nearest(lon, lat, mapTolerance)
+	}
+	if !ok {
+		return "", errors.New("no country at this point")
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		return "", err
+	}
+	if country, ok := countries.ByISO3(feature.ID); ok {
+		return country.Name, nil
+	}
+	return feature.Name, nil
+}
+
+// encodeGeoMap builds the map of the countries with the given ISO alpha-2
+// codes, all of them when codes is nil, simplified at tolerance.
+func encodeGeoMap(codes map[string]bool, tolerance float64) (*geoMap, error) {
+	world, err := loadWorld()
+	if err != nil {
+		return nil, err
+	}
+
+	var features []geo.Feature
+	for i := range world.Features {
+		feature := &world.Features[i]
+		if codes != nil {
+			if code, ok := catalog.ISO2(feature.ID); !ok || !codes[code] {
+				continue
+			}
+		}
+		features = append(features, feature.Simplify(tolerance))
+	}
+
+	body, err := geo.Encode(features)
+	if err != nil {
+		return nil, err
+	}
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(body)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:16])
+	return &geoMap{
+		body:        body,
+		gzipped:     gzipped.Bytes(),
+		etag:        `"` + hash + `"`,
+		gzippedEtag: `"` + hash + `-gz"`,
+	}, nil
+}
+
+// etagMatches reports whether an If-None-Match header, a comma separated
+// list of entity tags or "*", lists etag. Tags are compared weakly, without
+// their "W/" prefix, as If-None-Match asks.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// geoHandler serves the country outlines for the map, from
+// frontend/static/countries.geo.json, so the game doesn't depend on any other
+// server. The outlines can be limited to the countries a room draws its
+// questions from, and simplified to download faster. Responses are gzipped
+// for clients that accept it and carry an ETag of their encoding to be
+// revalidated cheaply.
+//
+// HTTP Method: GET
+// Query Parameters:
+//   - room: Only the countries of the room: those of its question pack, or
+//     of the catalog, within the room's region if it has one
+//   - pack: Only the countries of a question pack, when no room is given
+//   - tolerance: Douglas–Peucker tolerance in degrees (0-1), 0 or none
+//     serves the outlines as they are. It is rounded down to 0.01, 0.05,
+//     0.1 or 0.5, see geoTolerances
+//
+// Response:
+//   - 200: A GeoJSON FeatureCollection
+//   - 304: Not modified, the If-None-Match header lists the ETag
+//   - 400: Invalid tolerance
+//   - 404: Room or pack not found
+func geoHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	tolerance := 0.0
+	if value := query.Get("tolerance"); value != "" {
+		var err error
+		tolerance, err = strconv.ParseFloat(value, 64)
+		if err != nil || tolerance < 0 || tolerance > 1 {
+			http.Error(w, "Invalid tolerance", http.StatusBadRequest)
+			return
+		}
+		tolerance = snapTolerance(tolerance)
+	}
+
+	packID, region := query.Get("pack"), ""
+	dataset := "all"
+	if code := query.Get("room"); code != "" {
+		mu.Lock()
+		room, ok := rooms[code]
+		if ok {
+			packID, region = room.PackID, room.Region
+		}
+		mu.Unlock()
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Room not found"})
+			return
+		}
+		dataset = "catalog"
+	}
+	if packID != "" {
+		dataset = "pack:" + packID
+	}
+
+	key := dataset + "|" + strings.ToLower(region) + "|" + strconv.FormatFloat(tolerance, 'f', -1, 64)
+	geoCacheMu.Lock()
+	encoded, ok := geoCache[key]
+	geoCacheMu.Unlock()
+
+	if !ok {
+		var codes map[string]bool
+		switch {
+		case packID != "":
+			pack, ok := loadPacks().Get(packID)
+			if !ok {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(ErrorResponse{Error: "Question pack not found"})
+				return
+			}
+			codes = make(map[string]bool)
+			for _, code := range pack.Codes {
+				codes[code] = true
+			}
+		case dataset == "catalog":
+			countries, err := loadCatalog()
+			if err != nil {
+				http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			codes = make(map[string]bool)
+			for _, country := range countries.Countries {
+				codes[country.Code] = true
+			}
+		}
+
+		// Like generateQuestions, a region keeps the countries in it
+		if region != "" {
+			countries, err := loadCatalog()
+			if err != nil {
+				http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			inside, err := regionCodes(region, countries)
+			if err != nil {
+				http.Error(w, "Failed to load the region: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			kept := make(map[string]bool)
+			for _, code := range inside {
+				if codes[code] {
+					kept[code] = true
+				}
+			}
+			codes = kept
+		}
+
+		var err error
+		encoded, err = encodeGeoMap(codes, tolerance)
+		if err != nil {
+			http.Error(w, "Failed to load country outlines: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		geoCacheMu.Lock()
+		if len(geoCache) >= maxGeoCache {
+			// Evict one map, any: the few "all" and "catalog" maps are
+			// asked for the most and come back at once if evicted
+			for old := range geoCache {
+				delete(geoCache, old)
+				break
+			}
+		}
+		geoCache[key] = encoded
+		geoCacheMu.Unlock()
+	}
+
+	body, etag := encoded.body, encoded.etag
+	gzipped := strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+	if gzipped {
+		body, etag = encoded.gzipped, encoded.gzippedEtag
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Vary", "Accept-Encoding")
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	if gzipped {
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
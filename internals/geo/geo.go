@@ -0,0 +1,289 @@
+// Package geo reads the country outlines of frontend/static/countries.geo.json
+// and finds the country at a point, so that answers given by clicking the map
+// can be checked on the server. It also simplifies the outlines for the map
+// served to the browser.
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// point is a longitude, latitude pair, in the order of GeoJSON.
+type point [2]float64
+
+// polygon is an outer ring followed by its holes.
+type polygon [][]point
+
+// box is a bounding box, used to skip most polygons quickly.
+type box struct {
+	minLon, minLat, maxLon, maxLat float64
+}
+
+func (b box) contains(p point) bool {
+	return p[0] >= b.minLon && p[0] <= b.maxLon && p[1] >= b.minLat && p[1] <= b.maxLat
+}
+
+// Feature is the outline of one country.
+type Feature struct {
+	ID   string // ISO 3166-1 alpha-3 code
+	Name string
+
+	polygons []polygon
+	bounds   box
+}
+
+// Contains reports whether the point lies inside the country, outside of
+// its holes.
+func (f *Feature) Contains(lon, lat float64) bool {
+	p := point{lon, lat}
+	if !f.bounds.contains(p) {
+		return false
+	}
+	for _, poly := range f.polygons {
+		if poly.contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the bounding box of the country's outer rings as west,
+// south, east and north, in the order of a GeoJSON "bbox".
+func (f *Feature) Bounds() [4]float64 {
+	return [4]float64{f.bounds.minLon, f.bounds.minLat, f.bounds.maxLon, f.bounds.maxLat}
+}
+
+func (poly polygon) contains(p point) bool {
+	if len(poly) == 0 || !ringContains(poly[0], p) {
+		return false
+	}
+	for _, hole := range poly[1:] {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains tests a point against a closed ring by casting a ray towards
+// increasing longitude and counting the edges it crosses.
+func ringContains(ring []point, p point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) &&
+			p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// World is the set of country outlines.
+type World struct {
+	Features []Feature
+}
+
+// Load reads a GeoJSON FeatureCollection of countries with Polygon and
+// MultiPolygon geometries, keyed by ISO alpha-3 "id" with a "name" property.
+func Load(file string) (*World, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection struct {
+		Features []struct {
+			ID         string `json:"id"`
+			Properties struct {
+				Name string `json:"name"`
+			} `json:"properties"`
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	w := &World{}
+	for i, f := range collection.Features {
+		feature := Feature{ID: f.ID, Name: f.Properties.Name}
+
+		switch f.Geometry.Type {
+		case "Polygon":
+			var poly polygon
+			err = json.Unmarshal(f.Geometry.Coordinates, &poly)
+			feature.polygons = []polygon{poly}
+		case "MultiPolygon":
+			err = json.Unmarshal(f.Geometry.Coordinates, &feature.polygons)
+		default:
+			err = fmt.Errorf("unsupported geometry %q", f.Geometry.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: feature %d (%s): %w", file, i, f.ID, err)
+		}
+
+		feature.bounds = bounds(feature.polygons)
+		w.Features = append(w.Features, feature)
+	}
+	return w, nil
+}
+
+// bounds returns the bounding box of the outer rings of the polygons.
+func bounds(polygons []polygon) box {
+	b := box{minLon: 180, minLat: 90, maxLon: -180, maxLat: -90}
+	for _, poly := range polygons {
+		if len(poly) == 0 {
+			continue
+		}
+		for _, p := range poly[0] {
+			b.minLon = min(b.minLon, p[0])
+			b.maxLon = max(b.maxLon, p[0])
+			b.minLat = min(b.minLat, p[1])
+			b.maxLat = max(b.maxLat, p[1])
+		}
+	}
+	return b
+}
+
+// Locate returns the country at the point, if any.
+func (w *World) Locate(lon, lat float64) (*Feature, bool) {
+	for i := range w.Features {
+		if w.Features[i].Contains(lon, lat) {
+			return &w.Features[i], true
+		}
+	}
+	return nil, false
+}
+
+// Nearest returns the country whose outline passes closest to the point, if
+// one passes within distance degrees of it. It finds the country of clicks
+// that fall just outside every outline, e.g. on a coast.
+func (w *World) Nearest(lon, lat, within float64) (*Feature, bool) {
+	p := point{lon, lat}
+	var nearest *Feature
+	best := within
+	for i := range w.Features {
+		f := &w.Features[i]
+		area := box{f.bounds.minLon - within, f.bounds.minLat - within, f.bounds.maxLon + within, f.bounds.maxLat + within}
+		if !area.contains(p) {
+			continue
+		}
+		for _, poly := range f.polygons {
+			for _, ring := range poly {
+				for j := 1; j < len(ring); j++ {
+					if d := distanceToSegment(p, ring[j-1], ring[j]); d <= best {
+						nearest, best = f, d
+					}
+				}
+			}
+		}
+	}
+	return nearest, nearest != nil
+}
+
+// Simplify returns the world with every feature simplified at tolerance, see
+// Feature.Simplify.
+func (w *World) Simplify(tolerance float64) *World {
+	simplified := &World{Features: make([]Feature, len(w.Features))}
+	for i := range w.Features {
+		simplified.Features[i] = w.Features[i].Simplify(tolerance)
+	}
+	return simplified
+}
+
+// Simplify returns a copy of the feature with its rings simplified by the
+// Douglas–Peucker algorithm: points closer than tolerance, in degrees, to the
+// simplified outline are dropped. Holes that collapse are dropped; outer
+// rings that collapse are kept as they are so that no country disappears.
+func (f *Feature) Simplify(tolerance float64) Feature {
+	simplified := Feature{ID: f.ID, Name: f.Name, bounds: f.bounds}
+	for _, poly := range f.polygons {
+		var rings polygon
+		for i, ring := range poly {
+			s := douglasPeucker(ring, tolerance)
+			switch {
+			case len(s) >= 4:
+				rings = append(rings, s)
+			case i == 0:
+				rings = append(rings, ring)
+			}
+		}
+		simplified.polygons = append(simplified.polygons, rings)
+	}
+	return simplified
+}
+
+// douglasPeucker simplifies a line, keeping its end points. A closed ring
+// stays closed. The line is left untouched.
+func douglasPeucker(line []point, tolerance float64) []point {
+	if len(line) < 3 {
+		return append([]point(nil), line...)
+	}
+
+	first, last := line[0], line[len(line)-1]
+	index, farthest := 0, -1.0
+	for i := 1; i < len(line)-1; i++ {
+		if d := distanceToSegment(line[i], first, last); d > farthest {
+			index, farthest = i, d
+		}
+	}
+
+	if farthest <= tolerance {
+		return []point{first, last}
+	}
+	left := douglasPeucker(line[:index+1], tolerance)
+	right := douglasPeucker(line[index:], tolerance)
+	return append(left[:len(left)-1], right...)
+}
+
+// distanceToSegment returns the distance from p to the segment ab.
+func distanceToSegment(p, a, b point) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = ((p[0]-a[0])*dx + (p[1]-a[1])*dy) / length
+		t = max(0, min(1, t))
+	}
+	return math.Hypot(p[0]-(a[0]+t*dx), p[1]-(a[1]+t*dy))
+}
+
+// Encode writes the features as a GeoJSON FeatureCollection, in the layout
+// Load reads.
+func Encode(features []Feature) ([]byte, error) {
+	type geometry struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}
+	type feature struct {
+		Type       string            `json:"type"`
+		ID         string            `json:"id"`
+		Properties map[string]string `json:"properties"`
+		Geometry   geometry          `json:"geometry"`
+	}
+
+	collection := struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}{Type: "FeatureCollection", Features: []feature{}}
+
+	for _, f := range features {
+		g := geometry{Type: "MultiPolygon", Coordinates: f.polygons}
+		if len(f.polygons) == 1 {
+			g = geometry{Type: "Polygon", Coordinates: f.polygons[0]}
+		}
+		collection.Features = append(collection.Features, feature{
+			Type:       "Feature",
+			ID:         f.ID,
+			Properties: map[string]string{"name": f.Name},
+			Geometry:   g,
+		})
+	}
+	return json.Marshal(collection)
+}
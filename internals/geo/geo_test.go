@@ -0,0 +1,330 @@
+package geo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// square returns the closed ring of a square, counter-clockwise from its
+// south-west corner.
+func square(lon, lat, size float64) []point {
+	return []point{{lon, lat}, {lon + size, lat}, {lon + size, lat + size}, {lon, lat + size}, {lon, lat}}
+}
+
+// testWorld is made of:
+//   - AAA: a 10° square at 0,0 with a 4° hole in the middle
+//   - BBB: a 10° square east of AAA, sharing its border at lon 10
+//   - CCC: two 2° islands, at 30,0 and 40,0
+const testWorld = `{"type": "FeatureCollection", "features": [
+	{"type": "Feature", "id": "AAA", "properties": {"name": "Aland"}, "geometry": {"type": "Polygon", "coordinates": [
+		[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+		[[3, 3], [7, 3], [7, 7], [3, 7], [3, 3]]
+	]}},
+	{"type": "Feature", "id": "BBB", "properties": {"name": "Beland"}, "geometry": {"type": "Polygon", "coordinates": [
+		[[10, 0], [20, 0], [20, 10], [10, 10], [10, 0]]
+	]}},
+	{"type": "Feature", "id": "CCC", "properties": {"name": "Ceeland"}, "geometry": {"type": "MultiPolygon", "coordinates": [
+		[[[30, 0], [32, 0], [32, 2], [30, 2], [30, 0]]],
+		[[[40, 0], [42, 0], [42, 2], [40, 2], [40, 0]]]
+	]}}
+]}`
+
+func loadTestWorld(t *testing.T) *World {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "world.geo.json")
+	if err := os.WriteFile(file, []byte(testWorld), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestRingContains(t *testing.T) {
+	ring := square(0, 0, 10)
+	tests := []struct {
+		name string
+		p    point
+		want bool
+	}{
+		{"inside", point{5, 5}, true},
+		{"outside", point{15, 5}, false},
+		{"beside, level with an edge", point{-5, 0}, false},
+		{"above", point{5, 15}, false},
+		{"close to a corner", point{9.999, 9.999}, true},
+		// Points on an edge are inside on the west side only, so that a
+		// border between two rings belongs to one of them
+		{"west edge", point{0, 5}, true},
+		{"east edge", point{10, 5}, false},
+	}
+	for _, tt := range tests {
+		if got := ringContains(ring, tt.p); got != tt.want {
+			t.Errorf("%s: ringContains(%v) = %v, want %v", tt.name, tt.p, got, tt.want)
+		}
+	}
+
+	// A concave ring, a "U" open to the north
+	u := []point{{0, 0}, {9, 0}, {9, 9}, {6, 9}, {6, 3}, {3, 3}, {3, 9}, {0, 9}, {0, 0}}
+	if ringContains(u, point{4.5, 6}) {
+		t.Error("the gap of a U-shaped ring is inside it")
+	}
+	if !ringContains(u, point{1.5, 6}) || !ringContains(u, point{7.5, 6}) {
+		t.Error("the arms of a U-shaped ring are outside it")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	w := loadTestWorld(t)
+
+	if len(w.Features) != 3 {
+		t.Fatalf("loaded %d features, want 3", len(w.Features))
+	}
+	a, c := w.Features[0], w.Features[2]
+	if a.ID != "AAA" || a.Name != "Aland" {
+		t.Errorf("first feature is %s %q, want AAA \"Aland\"", a.ID, a.Name)
+	}
+	if len(a.polygons) != 1 || len(a.polygons[0]) != 2 {
+		t.Errorf("AAA has %d polygons, want 1 with a hole", len(a.polygons))
+	}
+	if len(c.polygons) != 2 {
+		t.Errorf("CCC has %d polygons, want 2", len(c.polygons))
+	}
+	if got, want := c.Bounds(), [4]float64{30, 0, 42, 2}; got != want {
+		t.Errorf("CCC bounds = %v, want %v", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"not JSON", `{"features": [`, "unexpected end"},
+		{"unsupported geometry", `{"features": [{"id": "PNT", "geometry": {"type": "Point", "coordinates": [0, 0]}}]}`, `unsupported geometry "Point"`},
+		{"bad coordinates", `{"features": [{"id": "BAD", "geometry": {"type": "Polygon", "coordinates": [0, 0]}}]}`, "feature 0 (BAD)"},
+	}
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "world.geo.json")
+		os.WriteFile(file, []byte(tt.content), 0o644)
+		_, err := Load(file)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Load error = %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.geo.json")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestLocate(t *testing.T) {
+	w := loadTestWorld(t)
+
+	tests := []struct {
+		name     string
+		lon, lat float64
+		want     string // empty for no country
+	}{
+		{"inside", 1, 1, "AAA"},
+		{"in the hole", 5, 5, ""},
+		{"between the hole and the border", 8, 5, "AAA"},
+		{"on the shared border", 10, 5, "BBB"},
+		{"east of the border", 15, 5, "BBB"},
+		{"first island", 31, 1, "CCC"},
+		{"second island", 41, 1, "CCC"},
+		{"between the islands", 36, 1, ""},
+		{"sea", -5, -5, ""},
+	}
+	for _, tt := range tests {
+		feature, ok := w.Locate(tt.lon, tt.lat)
+		got := ""
+		if ok {
+			got = feature.ID
+		}
+		if got != tt.want {
+			t.Errorf("%s: Locate(%v, %v) = %q, want %q", tt.name, tt.lon, tt.lat, got, tt.want)
+		}
+	}
+}
+
+func TestLocateBorderOnce(t *testing.T) {
+	w := loadTestWorld(t)
+
+	// Points on the border of two countries belong to exactly one of them
+	for _, lat := range []float64{0.5, 2, 5, 9.5} {
+		in := 0
+		for i := range w.Features {
+			if w.Features[i].Contains(10, lat) {
+				in++
+			}
+		}
+		if in != 1 {
+			t.Errorf("point 10,%v is in %d countries, want 1", lat, in)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	w := loadTestWorld(t)
+
+	tests := []struct {
+		name     string
+		lon, lat float64
+		within   float64
+		want     string
+	}{
+		{"just off the coast", -0.04, 5, 0.05, "AAA"},
+		{"too far off the coast", -0.1, 5, 0.05, ""},
+		{"closer to the second island", 38.5, 1, 2, "CCC"},
+		{"nearer the east country", 10.01, 5, 0.05, "BBB"},
+		{"inside the hole, near its edge", 3.02, 5, 0.05, "AAA"},
+	}
+	for _, tt := range tests {
+		feature, ok := w.Nearest(tt.lon, tt.lat, tt.within)
+		got := ""
+		if ok {
+			got = feature.ID
+		}
+		if got != tt.want {
+			t.Errorf("%s: Nearest(%v, %v, %v) = %q, want %q", tt.name, tt.lon, tt.lat, tt.within, got, tt.want)
+		}
+	}
+}
+
+func TestDouglasPeucker(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      []point
+		tolerance float64
+		want      []point
+	}{
+		{
+			"collinear points dropped",
+			[]point{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
+			0.1,
+			[]point{{0, 0}, {3, 0}},
+		},
+		{
+			"peak kept",
+			[]point{{0, 0}, {1, 0.52}, {2, 1}, {3, 0.48}, {4, 0}},
+			0.1,
+			[]point{{0, 0}, {2, 1}, {4, 0}},
+		},
+		{
+			"zero tolerance keeps every corner",
+			[]point{{0, 0}, {1, 0.05}, {2, 0}},
+			0,
+			[]point{{0, 0}, {1, 0.05}, {2, 0}},
+		},
+		{
+			"short line unchanged",
+			[]point{{0, 0}, {1, 1}},
+			10,
+			[]point{{0, 0}, {1, 1}},
+		},
+	}
+	for _, tt := range tests {
+		got := douglasPeucker(tt.line, tt.tolerance)
+		if !equalPoints(got, tt.want) {
+			t.Errorf("%s: douglasPeucker = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	line := []point{{0, 0}, {1, 0}, {2, 0}}
+	douglasPeucker(line, 1)
+	if !equalPoints(line, []point{{0, 0}, {1, 0}, {2, 0}}) {
+		t.Errorf("douglasPeucker changed its input to %v", line)
+	}
+}
+
+func TestDouglasPeuckerClosedRing(t *testing.T) {
+	// A square with extra points along its edges
+	ring := []point{{0, 0}, {5, 0}, {10, 0}, {10, 5}, {10, 10}, {5, 10.01}, {0, 10}, {0, 5}, {0, 0}}
+
+	got := douglasPeucker(ring, 0.1)
+	want := []point{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	if !equalPoints(got, want) {
+		t.Errorf("douglasPeucker = %v, want %v", got, want)
+	}
+	if got[0] != got[len(got)-1] {
+		t.Errorf("simplified ring is not closed: %v", got)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	w := loadTestWorld(t)
+
+	// The 4° hole of AAA collapses at a tolerance of 5°, its 10° outer ring
+	// doesn't
+	a := w.Features[0].Simplify(5)
+	if len(a.polygons) != 1 || len(a.polygons[0]) != 1 {
+		t.Fatalf("simplified AAA has polygons %v, want one without its hole", a.polygons)
+	}
+	if !a.Contains(5, 5) {
+		t.Error("the hole of AAA was kept")
+	}
+
+	// The 2° islands of CCC collapse at a tolerance of 5°, but are kept
+	// whole so that the country doesn't disappear
+	c := w.Features[2].Simplify(5)
+	if len(c.polygons) != 2 {
+		t.Fatalf("simplified CCC has %d polygons, want 2", len(c.polygons))
+	}
+	for i, poly := range c.polygons {
+		if !equalPoints(poly[0], w.Features[2].polygons[i][0]) {
+			t.Errorf("island %d changed to %v", i, poly[0])
+		}
+	}
+
+	for _, f := range w.Simplify(0.5).Features {
+		for _, poly := range f.polygons {
+			for _, ring := range poly {
+				if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+					t.Errorf("%s has an open or degenerate ring %v", f.ID, ring)
+				}
+			}
+		}
+	}
+	if len(w.Features[0].polygons[0]) != 2 {
+		t.Error("Simplify changed the original feature")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	w := loadTestWorld(t)
+
+	data, err := Encode(w.Features)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "encoded.geo.json")
+	os.WriteFile(file, data, 0o644)
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Features) != len(w.Features) {
+		t.Fatalf("round trip gave %d features, want %d", len(loaded.Features), len(w.Features))
+	}
+	for i, f := range loaded.Features {
+		if f.ID != w.Features[i].ID || f.Name != w.Features[i].Name || len(f.polygons) != len(w.Features[i].polygons) {
+			t.Errorf("feature %d is %s %q with %d polygons after the round trip", i, f.ID, f.Name, len(f.polygons))
+		}
+	}
+}
+
+func equalPoints(a, b []point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
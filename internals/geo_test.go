@@ -0,0 +1,118 @@
+package internals
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"old", W/"abc"`, true},
+		{`"old",W/"abc" , "older"`, true},
+		{`*`, true},
+		{`"abc-gz"`, false},
+		{`"old", "older"`, false},
+		{`abc`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%s) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestGeoHandlerEncodings(t *testing.T) {
+	chdirRoot(t)
+
+	get := func(acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/geo?tolerance=0.5", nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		geoHandler(w, r)
+		return w
+	}
+
+	plain, gzipped := get("", ""), get("gzip, deflate", "")
+	if plain.Code != http.StatusOK || gzipped.Code != http.StatusOK {
+		t.Fatalf("status %d and %d, want 200", plain.Code, gzipped.Code)
+	}
+	plainTag, gzipTag := plain.Header().Get("ETag"), gzipped.Header().Get("ETag")
+	if plainTag == gzipTag || !strings.HasSuffix(gzipTag, `-gz"`) {
+		t.Errorf("ETags %s and %s, want one per encoding", plainTag, gzipTag)
+	}
+	if gzipped.Header().Get("Content-Encoding") != "gzip" || gzipped.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("gzipped response headers %v", gzipped.Header())
+	}
+
+	tests := []struct {
+		name                        string
+		acceptEncoding, ifNoneMatch string
+		want                        int
+	}{
+		{"same encoding", "", plainTag, http.StatusNotModified},
+		{"weak tag in a list", "gzip", `"old", W/` + gzipTag, http.StatusNotModified},
+		{"tag of the other encoding", "gzip", plainTag, http.StatusOK},
+		{"tag of the other encoding, plain", "", gzipTag, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := get(tt.acceptEncoding, tt.ifNoneMatch); w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestGeoHandlerRoomRegion(t *testing.T) {
+	chdirRoot(t)
+
+	room := &game.Room{Code: "T043", Region: "nordics"}
+	mu.Lock()
+	rooms[room.Code] = room
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(rooms, room.Code)
+		mu.Unlock()
+	})
+
+	w := httptest.NewRecorder()
+	geoHandler(w, httptest.NewRequest(http.MethodGet, "/api/geo?room=T043&tolerance=0.5", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	var collection struct {
+		Features []struct {
+			ID string `json:"id"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&collection); err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for _, feature := range collection.Features {
+		code, _ := catalog.ISO2(feature.ID)
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	// The Nordic countries of the catalog
+	if got := strings.Join(codes, ","); got != "DK,FI,IS,NO,SE" {
+		t.Errorf("room map shows %s, want DK,FI,IS,NO,SE", got)
+	}
+}
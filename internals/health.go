@@ -0,0 +1,232 @@
+package internals
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+// Version and Commit identify the build, set with
+//
+//	go build -ldflags "-X github.com/adimail/fun-with-flags/internals.Version=v1.2.0 -X github.com/adimail/fun-with-flags/internals.Commit=$(git rev-parse HEAD)"
+//
+// Without them the commit is taken from the VCS information Go stamps into
+// the binary, if any.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// dataDir holds the files the server saves, see saveData.
+const dataDir = "./data"
+
+// startedAt is when the server started, for the uptime of versionHandler.
+var startedAt = time.Now()
+
+// draining is set once the server is shutting down, see Drain.
+var draining atomic.Bool
+
+// datasetFiles are the data files reported by versionHandler, by name.
+var datasetFiles = map[string]string{
+	"countries": countriesFile,
+	"aliases":   aliasesFile,
+	"outlines":  worldFile,
+}
+
+var (
+	datasetVersions map[string]string
+	datasetOnce     sync.Once
+)
+
+// loadDatasetVersions returns the first 12 hex digits of the SHA-256 of each
+// of the datasetFiles. The data files are only read at startup, so Router
+// hashes them then too, and the hashes tell which version a running server
+// serves even if the files changed on disk since.
+func loadDatasetVersions() map[string]string {
+	datasetOnce.Do(func() {
+		datasetVersions = make(map[string]string, len(datasetFiles))
+		for name, file := range datasetFiles {
+			version, err := fileHash(file)
+			if err != nil {
+				slog.Error("Failed to hash data file", "file", file, "error", err)
+				version = "unknown"
+			}
+			datasetVersions[name] = version
+		}
+	})
+	return datasetVersions
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+// buildCommit returns Commit, or the VCS revision stamped by the Go
+// toolchain.
+func buildCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// Drain marks the server as shutting down: /readyz fails from then on, so
+// load balancers stop sending new players before the server stops.
+func Drain() {
+	draining.Store(true)
+	slog.Info("Draining, readiness checks now fail")
+}
+
+// Shutdown ends the rooms still open, telling their players and spectators
+// with a "room_closed" event, and saves the data to disk. It is the last step
+// of a graceful shutdown, once the HTTP server has stopped.
+func Shutdown() {
+	mu.Lock()
+	open := make([]*game.Room, 0, len(rooms))
+	for _, room := range rooms {
+		open = append(open, room)
+	}
+	mu.Unlock()
+
+	for _, room := range open {
+		broadcastToRoom(room, map[string]interface{}{
+			"event": "room_closed",
+			"data": map[string]interface{}{
+				"reason": "The server is restarting",
+			},
+		})
+		closeRoom(room)
+	}
+	slog.Info("Closed rooms", "rooms", len(open))
+
+	saveData()
+}
+
+// healthzHandler tells that the process is alive and serving requests.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"status": "ok"}
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+	})
+}
+
+// readyzHandler tells whether the server can take players: the country
+// catalog and outlines are loaded, the data directory is writable and the
+// server is not shutting down (see Drain).
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"status": "ready", "checks"}
+//   - 503: {"status": "unavailable", "checks"}, with the failing checks
+//     giving their error instead of "ok"
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"catalog":  "ok",
+		"outlines": "ok",
+		"storage":  "ok",
+		"draining": "ok",
+	}
+	if _, err := loadCatalog(); err != nil {
+		checks["catalog"] = err.Error()
+	}
+	if _, err := loadWorld(); err != nil {
+		checks["outlines"] = err.Error()
+	}
+	if err := checkStorage(); err != nil {
+		checks["storage"] = err.Error()
+	}
+	if draining.Load() {
+		checks["draining"] = "the server is shutting down"
+	}
+
+	status, ready := http.StatusOK, "ready"
+	for _, result := range checks {
+		if result != "ok" {
+			status, ready = http.StatusServiceUnavailable, "unavailable"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": ready,
+		"checks": checks,
+	})
+}
+
+// checkStorage checks that files can be written to the data directory, by
+// writing and removing a temporary file.
+func checkStorage() error {
+	f, err := os.CreateTemp(dataDir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// versionHandler returns the build and dataset versions of the server, how
+// long it has been up and how busy it is.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"version", "commit", "goVersion", "datasets": {"countries",
+//     "aliases", "outlines"}, "startedAt", "uptimeSeconds", "rooms",
+//     "sockets", "draining"}
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	goVersion := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		goVersion = info.GoVersion
+	}
+
+	mu.Lock()
+	open := len(rooms)
+	mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":       Version,
+		"commit":        buildCommit(),
+		"goVersion":     goVersion,
+		"datasets":      loadDatasetVersions(),
+		"startedAt":     startedAt.Format(time.RFC3339),
+		"uptimeSeconds": int(time.Since(startedAt).Seconds()),
+		"rooms":         open,
+		"sockets":       openSockets.Load(),
+		"draining":      draining.Load(),
+	})
+}
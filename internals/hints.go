@@ -0,0 +1,179 @@
+package internals
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/websocket"
+)
+
+// maxMapRetries caps the wrong clicks allowed per map question.
+const maxMapRetries = 3
+
+// earthRadius is the mean radius of the Earth in kilometres.
+const earthRadius = 6371.0
+
+// compassPoints are the directions of mapHint, clockwise from north.
+var compassPoints = []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// validateMapRetries checks the retries of a room's map questions. Retries
+// are only available in "MAP" rooms.
+func validateMapRetries(req *game.CreateRoomRequest) error {
+	if req.MapRetries < 0 || req.MapRetries > maxMapRetries {
+		return errors.New("map retries must be between 0 and 3")
+	}
+	if req.MapRetries > 0 && req.GameType != "MAP" {
+		return errors.New("map retries can only be given in map rooms")
+	}
+	return nil
+}
+
+// offerHint handles a wrong click on a "MAP" question in a room with
+// MapRetries: while the player has retries left, the answer is not recorded
+// and the player gets an "answer_hint" event pointing them to the country
+// instead (see mapHint). Each retry lowers the points of the question, see
+// answerPoints.
+//
+// Parameters:
+//   - room: Pointer to the Room instance
+//   - player: The player who clicked
+//   - conn: The player's WebSocket connection, used for the hint
+//   - questionIndex: The index of the question answered
+//   - answer: The country clicked, see locateAnswer
+//   - lon, lat: The clicked point, in degrees
+//
+// Returns:
+//   - bool: true if a hint was sent, and the answer must not be recorded
+func offerHint(room *game.Room, player *game.Player, conn *websocket.Conn, questionIndex int, answer string, lon, lat float64) bool {
+	question := room.Questions[strconv.Itoa(questionIndex)]
+	if room.MapRetries == 0 || question.Type != "MAP" || checkAnswer(question, answer, room.Tolerance).Accepted {
+		return false
+	}
+
+	mu.Lock()
+	if room.Synchronized {
+		// Answers outside the open round are refused by submitRoundAnswer
+		round := room.Round
+		if round == nil || round.Index != questionIndex {
+			mu.Unlock()
+			return false
+		}
+		if _, answered := round.Answers[player.ID]; answered {
+			mu.Unlock()
+			return false
+		}
+	}
+	if player.MapMisses == nil {
+		player.MapMisses = make(map[int]int)
+	}
+	misses := player.MapMisses[questionIndex]
+	if misses >= room.MapRetries {
+		mu.Unlock()
+		return false
+	}
+	player.MapMisses[questionIndex] = misses + 1
+	mu.Unlock()
+
+	hint, err := mapHint(question, answer, lon, lat)
+	if err != nil {
+		slog.Error("Failed to compute map hint", "room", room.Code, "player", player.ID, "error", err)
+		return false
+	}
+	hint["question_index"] = questionIndex
+	hint["retries_left"] = room.MapRetries - misses - 1
+
+	message := map[string]interface{}{
+		"event": "answer_hint",
+		"data":  hint,
+	}
+	auditMessage(room, player.ID, message)
+	if err := writeJSON(conn, message); err != nil {
+		slog.Warn("Error sending hint to player", "room", room.Code, "player", player.ID, "error", err)
+	}
+	return true
+}
+
+// mapHint tells how far off a wrong click on the map is: the distance and
+// compass direction from the clicked country to the country asked about,
+// both taken at their coordinates in data/countries.csv, and how close that
+// is, from "hot" to "cold". Clicks on countries outside the catalog are
+// measured from the clicked point.
+//
+// Parameters:
+//   - question: The "MAP" question answered
+//   - answer: The country clicked, see locateAnswer
+//   - lon, lat: The clicked point, in degrees
+//
+// Returns:
+//   - map[string]interface{}: {"chosen_answer", "distance_km", "bearing",
+//     "direction", "feedback"}
+//   - error: The catalog can't be loaded or lacks the country asked about
+func mapHint(question *game.Question, answer string, lon, lat float64) (map[string]interface{}, error) {
+	countries, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	target, ok := countries.ByCode(question.Code)
+	if !ok {
+		return nil, errors.New("unknown country " + question.Code)
+	}
+	if clicked, ok := countries.ByName(answer); ok {
+		lon, lat = clicked.Lon, clicked.Lat
+	}
+
+	distance := greatCircleDistance(lat, lon, target.Lat, target.Lon)
+	bearing := initialBearing(lat, lon, target.Lat, target.Lon)
+
+	var feedback string
+	switch {
+	case distance < 500:
+		feedback = "hot"
+	case distance < 1500:
+		feedback = "warm"
+	case distance < 3000:
+		feedback = "cool"
+	default:
+		feedback = "cold"
+	}
+
+	return map[string]interface{}{
+		"chosen_answer": answer,
+		"distance_km":   int(math.Round(distance)),
+		"bearing":       int(math.Round(bearing)) % 360,
+		"direction":     compassPoints[int(math.Round(bearing/45))%len(compassPoints)],
+		"feedback":      feedback,
+	}, nil
+}
+
+// answerPoints returns the points of a correct answer: 1, or in rooms with
+// map retries, MapRetries+1 for a first click, less one per retry used.
+func answerPoints(room *game.Room, player *game.Player, questionIndex int) int {
+	if room.MapRetries == 0 {
+		return 1
+	}
+	return room.MapRetries + 1 - player.MapMisses[questionIndex]
+}
+
+// greatCircleDistance returns the distance in kilometres between two points
+// given in degrees, by the haversine formula.
+func greatCircleDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1, lat2 = lat1*math.Pi/180, lat2*math.Pi/180
+	dLat, dLon := lat2-lat1, (lon2-lon1)*math.Pi/180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(min(1, math.Sqrt(a)))
+}
+
+// initialBearing returns the direction to head from the first point to reach
+// the second along a great circle, in degrees clockwise from north (0-360).
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1, lat2 = lat1*math.Pi/180, lat2*math.Pi/180
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
@@ -0,0 +1,32 @@
+// Package jsonfile saves the stores of the server (statistics, accounts,
+// profiles...) as JSON files that are never left half written.
+//
+// Stores call Write with their lock held and only clear their dirty flag
+// once it returns nil: changes made during a save are then not marked as
+// saved, and a failed save is retried on the next one.
+package jsonfile
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Write encodes v as JSON into file, with the given permissions. The data
+// goes to a temporary file next to it first, which is then renamed over
+// file, so a crash leaves either the old or the new file.
+func Write(file string, v interface{}, perm os.FileMode) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.json")
+
+	if err := Write(file, map[string]int{"a": 1}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(file, map[string]int{"b": 2}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"b":2}` {
+		t.Errorf("file holds %s, want the last value written", data)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode %v, want 0600", info.Mode().Perm())
+	}
+	if _, err := os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// Values that can't be encoded leave the file alone
+	file := filepath.Join(dir, "data.json")
+	Write(file, []int{1}, 0o644)
+	if err := Write(file, func() {}, 0o644); err == nil {
+		t.Error("Write of a func succeeded")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "[1]" {
+		t.Errorf("file holds %s after a failed write, want [1]", data)
+	}
+
+	if err := Write(filepath.Join(dir, "missing", "data.json"), 1, 0o644); err == nil {
+		t.Error("Write to a missing directory succeeded")
+	}
+}
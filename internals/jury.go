@@ -0,0 +1,242 @@
+package internals
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+// juryPoints are the points each jury awards, best answer first, as in the
+// Eurovision Song Contest.
+var juryPoints = []int{12, 10, 8, 7, 6, 5, 4, 3, 2, 1}
+
+// Pacing of the scoreboard reveal
+const (
+	juryIntroPause  = 1500 * time.Millisecond
+	juryPointsPause = 1500 * time.Millisecond
+	douzePointsWait = 2500 * time.Millisecond
+
+	// juryRevealTime is how long one jury takes at full pace
+	juryRevealTime = juryIntroPause + 3*juryPointsPause + douzePointsWait
+	// maxFinaleTime caps the whole reveal, longer games are revealed
+	// faster
+	maxFinaleTime = 60 * time.Second
+)
+
+// juryPace returns the share of the full pauses the reveal of the given
+// number of juries can take, so that it lasts at most maxFinaleTime.
+func juryPace(juries int) float64 {
+	full := time.Duration(juries) * juryRevealTime
+	if full <= maxFinaleTime {
+		return 1
+	}
+	return float64(maxFinaleTime) / float64(full)
+}
+
+// juryVotes converts the players' performance on one question into
+// Eurovision points. Each question acts as a jury: players who answered it
+// correctly are ranked by how fast they answered and receive 12, 10, 8, 7 ...
+// 1 points in that order. Wrong answers get nothing. The caller must hold
+// mu.
+func juryVotes(players []*game.Player, questionIndex int) []map[string]interface{} {
+	type entry struct {
+		player  *game.Player
+		elapsed time.Duration
+	}
+
+	var correct []entry
+	for _, player := range players {
+		for _, answer := range player.Answers {
+			if answer.QuestionIndex == questionIndex && answer.Correct {
+				correct = append(correct, entry{player, answer.Elapsed})
+			}
+		}
+	}
+	sort.SliceStable(correct, func(i, j int) bool {
+		return correct[i].elapsed < correct[j].elapsed
+	})
+
+	votes := []map[string]interface{}{}
+	for i, e := range correct {
+		if i >= len(juryPoints) {
+			break
+		}
+		votes = append(votes, map[string]interface{}{
+			"id":       e.player.ID,
+			"username": e.player.Username,
+			"points":   juryPoints[i],
+		})
+	}
+	return votes
+}
+
+// runJuryFinale reveals the scoreboard of a room, Eurovision style, once all
+// players have finished. Every question is a jury whose points are
+// announced in turn: first the points from 1 to 8 together, then the 10
+// points and, after a pause, the 12 points. The final ranking is by points
+// awarded, ties broken by score. The reveal is sped up for long games so
+// that it takes at most maxFinaleTime. The room is closed once the final
+// ranking is out, even if its time limit ran out during the finale.
+//
+// Parameters:
+//   - room: Pointer to the Room instance, must have JuryFinale set
+//
+// Events broadcast to the room:
+//   - "jury_start": The jury (question number and country) about to vote
+//   - "jury_points": A batch of points, with every player's running total
+//   - "douze_points": The 12 points of the jury
+//   - "scoreboard_final": The final ranking
+func runJuryFinale(room *game.Room) {
+	// Rooms closed meanwhile, e.g. when the time ran out, get no finale
+	mu.Lock()
+	if room.FinaleRunning || room.FinaleDone || rooms[room.Code] != room {
+		mu.Unlock()
+		return
+	}
+	room.FinaleRunning = true
+
+	// Every jury votes on the answers as they are now, players still
+	// connected can't change them during the reveal
+	players := roomParticipants(room)
+	for _, player := range players {
+		player.JuryPoints = 0
+	}
+	votes := make([][]map[string]interface{}, len(room.Questions))
+	juries := 0
+	for i := range votes {
+		votes[i] = juryVotes(players, i)
+		if len(votes[i]) > 0 {
+			juries++
+		}
+	}
+	mu.Unlock()
+
+	pace := juryPace(juries)
+	pause := func(d time.Duration) {
+		time.Sleep(time.Duration(float64(d) * pace))
+	}
+
+	// award adds the points of a batch of votes and returns every player's
+	// running total
+	award := func(batch []map[string]interface{}) map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		awardJuryPoints(players, batch)
+		t := make(map[string]int)
+		for _, player := range players {
+			t[player.Username] = player.JuryPoints
+		}
+		return t
+	}
+
+	for i := 0; i < len(room.Questions); i++ {
+		if len(votes[i]) == 0 {
+			continue
+		}
+		question := room.Questions[strconv.Itoa(i)]
+		jury := fmt.Sprintf("Question %d", i+1)
+		if countries, err := loadCatalog(); err == nil {
+			if country, ok := countries.ByCode(question.Code); ok {
+				jury = fmt.Sprintf("Question %d: %s", i+1, country.Name)
+			}
+		}
+
+		broadcastToRoom(room, map[string]interface{}{
+			"event": "jury_start",
+			"data": map[string]interface{}{
+				"jury":  jury,
+				"index": i,
+			},
+		})
+		pause(juryIntroPause)
+
+		// Votes are ordered 12, 10, 8 ... and revealed from the lowest
+		var low, ten, twelve []map[string]interface{}
+		for _, vote := range votes[i] {
+			switch vote["points"] {
+			case 12:
+				twelve = append(twelve, vote)
+			case 10:
+				ten = append(ten, vote)
+			default:
+				low = append(low, vote)
+			}
+		}
+
+		for _, batch := range [][]map[string]interface{}{low, ten} {
+			if len(batch) == 0 {
+				continue
+			}
+			totals := award(batch)
+			broadcastToRoom(room, map[string]interface{}{
+				"event": "jury_points",
+				"data": map[string]interface{}{
+					"jury":   jury,
+					"votes":  batch,
+					"totals": totals,
+				},
+			})
+			pause(juryPointsPause)
+		}
+
+		pause(douzePointsWait)
+		totals := award(twelve)
+		broadcastToRoom(room, map[string]interface{}{
+			"event": "douze_points",
+			"data": map[string]interface{}{
+				"jury":   jury,
+				"votes":  twelve,
+				"totals": totals,
+			},
+		})
+		pause(juryPointsPause)
+	}
+
+	mu.Lock()
+	sort.SliceStable(players, func(i, j int) bool {
+		if players[i].JuryPoints != players[j].JuryPoints {
+			return players[i].JuryPoints > players[j].JuryPoints
+		}
+		return players[i].Score > players[j].Score
+	})
+
+	ranking := []map[string]interface{}{}
+	for i, player := range players {
+		ranking = append(ranking, map[string]interface{}{
+			"rank":     i + 1,
+			"id":       player.ID,
+			"username": player.Username,
+			"points":   player.JuryPoints,
+			"score":    player.Score,
+			"team":     player.Team,
+		})
+	}
+	room.FinaleRunning = false
+	room.FinaleDone = true
+	mu.Unlock()
+
+	broadcastToRoom(room, map[string]interface{}{
+		"event": "scoreboard_final",
+		"data": map[string]interface{}{
+			"ranking": ranking,
+		},
+	})
+
+	recordGameResults(room)
+	closeRoom(room)
+}
+
+// awardJuryPoints adds the points of a batch of votes to the players' totals.
+// The caller must hold mu.
+func awardJuryPoints(players []*game.Player, votes []map[string]interface{}) {
+	for _, vote := range votes {
+		for _, player := range players {
+			if player.ID == vote["id"] {
+				player.JuryPoints += vote["points"].(int)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package internals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+func TestJuryPace(t *testing.T) {
+	for _, juries := range []int{0, 1, 7, 10, 25} {
+		pace := juryPace(juries)
+		if pace <= 0 || pace > 1 {
+			t.Errorf("juryPace(%d) = %v, want within (0, 1]", juries, pace)
+		}
+		total := time.Duration(float64(time.Duration(juries)*juryRevealTime) * pace)
+		if total > maxFinaleTime+time.Millisecond {
+			t.Errorf("%d juries take %v, more than %v", juries, total, maxFinaleTime)
+		}
+	}
+	if pace := juryPace(5); pace != 1 {
+		t.Errorf("juryPace(5) = %v, want 1, short finales are not sped up", pace)
+	}
+}
+
+func TestJuryVotes(t *testing.T) {
+	player := func(id string, answers ...game.AnswerRecord) *game.Player {
+		return &game.Player{ID: id, Username: id, Answers: answers}
+	}
+	answer := func(correct bool, ms int) game.AnswerRecord {
+		return game.AnswerRecord{QuestionIndex: 0, Correct: correct, Elapsed: time.Duration(ms) * time.Millisecond}
+	}
+
+	players := []*game.Player{
+		player("slow", answer(true, 900)),
+		player("wrong", answer(false, 100)),
+		player("fast", answer(true, 200)),
+		player("none"),
+	}
+	votes := juryVotes(players, 0)
+
+	want := []struct {
+		id     string
+		points int
+	}{{"fast", 12}, {"slow", 10}}
+	if len(votes) != len(want) {
+		t.Fatalf("juryVotes = %v, want %v", votes, want)
+	}
+	for i, w := range want {
+		if votes[i]["id"] != w.id || votes[i]["points"] != w.points {
+			t.Errorf("vote %d = %v, want %s with %d points", i, votes[i], w.id, w.points)
+		}
+	}
+
+	if votes := juryVotes(players, 1); len(votes) != 0 {
+		t.Errorf("juryVotes of an unanswered question = %v, want none", votes)
+	}
+}
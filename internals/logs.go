@@ -2,25 +2,126 @@ package internals
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The environment variables configuring the logs, see SetupLogging.
+const (
+	logLevelEnv  = "LOG_LEVEL"
+	logFormatEnv = "LOG_FORMAT"
 )
 
-// Logging middleware
+// requestIDHeader carries the ID of a request. An ID given by a proxy in
+// front of the server is kept, so its logs and ours can be matched.
+const requestIDHeader = "X-Request-ID"
+
+// SetupLogging makes the default slog logger write to stderr at the level of
+// LOG_LEVEL ("debug", "info", "warn" or "error", "info" if unset), as JSON
+// lines if LOG_FORMAT is "json" and as key=value text otherwise. Calls to the
+// log package go through the same logger.
+func SetupLogging() {
+	var level slog.Level
+	if value := os.Getenv(logLevelEnv); value != "" {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %s %q, logging at info level\n", logLevelEnv, value)
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv(logFormatEnv), "json") {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+type loggerKey struct{}
+
+// requestLogger returns the logger of a request, which adds its request ID
+// to every line (see loggingMiddleware), or the default logger.
+func requestLogger(r *http.Request) *slog.Logger {
+	if logger, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// requestID returns the request ID given in the request headers, or a new
+// random one if there is none or it doesn't look like an ID.
+func requestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if id != "" && len(id) <= 64 && !strings.ContainsFunc(id, func(c rune) bool {
+		return c <= ' ' || c > '~'
+	}) {
+		return id
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loggingMiddleware gives every request an ID, sent back in the X-Request-ID
+// header, and a logger carrying it for the handlers (see requestLogger). Once
+// the request is answered it logs the method, route, status, bytes written
+// and latency. Static files and probes are logged at debug level only. For /ws the line
+// is written when the socket closes, and the latency is how long it was open.
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := requestID(r)
+		w.Header().Set(requestIDHeader, id)
+
+		logger := slog.Default().With("request_id", id)
+		r = r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger))
+
 		ww := &statusCodeResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(ww, r)
 
-		log.Printf("Status: %d, Route: %s, Method: %s\n", ww.statusCode, r.URL.Path, r.Method)
+		route := routeTemplate(r)
+		level := slog.LevelInfo
+		if strings.HasPrefix(route, "/static/") || route == "/healthz" || route == "/readyz" {
+			level = slog.LevelDebug
+		}
+		logger.Log(r.Context(), level, "Request",
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", ww.statusCode,
+			"bytes", ww.bytes,
+			"latency", time.Since(start),
+		)
 	})
 }
 
+// routeTemplate returns the template of the route a request matched, such
+// as "/api/room/{id}", or "unmatched".
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
 type statusCodeResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (w *statusCodeResponseWriter) WriteHeader(statusCode int) {
@@ -30,12 +131,23 @@ func (w *statusCodeResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+func (w *statusCodeResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
 func (w *statusCodeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hijacker, ok := w.ResponseWriter.(http.Hijacker)
 	if !ok {
 		return nil, nil, fmt.Errorf("ResponseWriter does not implement http.Hijacker")
 	}
-	return hijacker.Hijack()
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		// WebSocket upgrades answer on the hijacked connection
+		w.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
 }
 
 func (w *statusCodeResponseWriter) Flush() {
@@ -0,0 +1,105 @@
+package internals
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/metrics"
+)
+
+// registry holds the server metrics served at /metrics, see metricsHandler.
+var registry = metrics.NewRegistry()
+
+var (
+	gamesStarted = registry.Counter("funwithflags_games_started_total",
+		"Multiplayer games started.")
+	gamesFinished = registry.Counter("funwithflags_games_finished_total",
+		"Multiplayer games finished, with their results recorded.")
+	questionsServed = registry.Counter("funwithflags_questions_served_total",
+		"Questions sent to players, by question type.", "mode")
+	answersTotal = registry.Counter("funwithflags_answers_total",
+		"Answers given, by question type and correctness.", "mode", "correct")
+	wsMessages = registry.Counter("funwithflags_websocket_messages_total",
+		"WebSocket messages received from players, by event.", "event")
+	broadcastDuration = registry.Histogram("funwithflags_broadcast_duration_seconds",
+		"Time taken to send a message to everyone in a room.",
+		[]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5})
+	roomsCleanedUp = registry.Counter("funwithflags_rooms_cleaned_up_total",
+		"Empty rooms deleted by the periodic cleanup.")
+	httpDuration = registry.Histogram("funwithflags_http_request_duration_seconds",
+		"Time taken to answer HTTP requests, by route, method and status.",
+		metrics.DefaultBuckets, "route", "method", "status")
+)
+
+func init() {
+	registry.GaugeFunc("funwithflags_active_rooms", "Rooms open.", func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return float64(len(rooms))
+	})
+	registry.GaugeFunc("funwithflags_websocket_connections", "WebSocket connections open.", func() float64 {
+		return float64(openSockets.Load())
+	})
+}
+
+// wsEvents are the events the /ws loop handles. Anything else a client
+// sends is counted as "other", so clients can't create new series, and is
+// left out of the audit log.
+var wsEvents = map[string]bool{
+	"leave":            true,
+	"join_team":        true,
+	"loadgame":         true,
+	"get_new_question": true,
+	"clean_room":       true,
+	"validate_answer":  true,
+}
+
+// countMessage counts a message received on the /ws loop.
+func countMessage(event string) {
+	if !wsEvents[event] {
+		event = "other"
+	}
+	wsMessages.Inc(event)
+}
+
+// countAnswer counts an answer by question type and correctness. Single
+// player clients report the type of their answers themselves, so unknown
+// types are counted as "other".
+func countAnswer(mode string, correct bool) {
+	if _, ok := questionGenerators[mode]; !ok {
+		mode = "other"
+	}
+	answersTotal.Inc(mode, strconv.FormatBool(correct))
+}
+
+// metricsMiddleware times every HTTP request, labelled with the route
+// template rather than the path so that room codes and usernames don't each
+// make a series. WebSocket connections, for games and replays, last long and
+// are counted by funwithflags_websocket_connections instead.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		if strings.HasPrefix(route, "/ws") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ww := &statusCodeResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(ww, r)
+		httpDuration.Observe(time.Since(start).Seconds(), route, r.Method, strconv.Itoa(ww.statusCode))
+	})
+}
+
+// metricsHandler serves the server metrics in the Prometheus text format.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: The metrics
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	registry.WriteTo(w)
+}
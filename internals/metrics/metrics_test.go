@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	started := r.Counter("games_started_total", "Games started.")
+	answers := r.Counter("answers_total", "Answers by mode\nand correctness.", "mode", "correct")
+	r.GaugeFunc("rooms", "Rooms open.", func() float64 { return 3 })
+	latency := r.Histogram("latency_seconds", `Latency in "seconds".`, []float64{0.1, 1}, "route")
+	r.Counter("unused_total", "Never incremented.", "event")
+
+	started.Inc()
+	started.Add(2)
+	answers.Inc("MCQ", "true")
+	answers.Inc("MAP", "false")
+	answers.Inc("MCQ", "true")
+	answers.Inc(`say "hi"\`, "false")
+	latency.Observe(0.05, "/api")
+	latency.Observe(0.1, "/api")
+	latency.Observe(0.5, "/api")
+	latency.Observe(3, "/api")
+	latency.Observe(0.2, "/ws")
+
+	want := `# HELP games_started_total Games started.
+# TYPE games_started_total counter
+games_started_total 3
+# HELP answers_total Answers by mode\nand correctness.
+# TYPE answers_total counter
+answers_total{mode="MAP",correct="false"} 1
+answers_total{mode="MCQ",correct="true"} 2
+answers_total{mode="say \"hi\"\\",correct="false"} 1
+# HELP rooms Rooms open.
+# TYPE rooms gauge
+rooms 3
+# HELP latency_seconds Latency in "seconds".
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/api",le="0.1"} 2
+latency_seconds_bucket{route="/api",le="1"} 3
+latency_seconds_bucket{route="/api",le="+Inf"} 4
+latency_seconds_sum{route="/api"} 3.65
+latency_seconds_count{route="/api"} 4
+latency_seconds_bucket{route="/ws",le="0.1"} 0
+latency_seconds_bucket{route="/ws",le="1"} 1
+latency_seconds_bucket{route="/ws",le="+Inf"} 1
+latency_seconds_sum{route="/ws"} 0.2
+latency_seconds_count{route="/ws"} 1
+# HELP unused_total Never incremented.
+# TYPE unused_total counter
+`
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteTo wrote:\n%s\nwant:\n%s", got, want)
+	}
+	if n != int64(b.Len()) {
+		t.Errorf("WriteTo returned %d bytes, wrote %d", n, b.Len())
+	}
+}
+
+func TestHistogramWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	r.Histogram("empty_seconds", "No observations.", []float64{1})
+
+	want := `# HELP empty_seconds No observations.
+# TYPE empty_seconds histogram
+empty_seconds_bucket{le="1"} 0
+empty_seconds_bucket{le="+Inf"} 0
+empty_seconds_sum 0
+empty_seconds_count 0
+`
+	var b strings.Builder
+	r.WriteTo(&b)
+	if got := b.String(); got != want {
+		t.Errorf("WriteTo wrote:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterValue(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("events_total", "Events.", "event")
+	c.Inc("join")
+	c.Add(2.5, "join")
+
+	if got := c.Value("join"); got != 3.5 {
+		t.Errorf(`Value("join") = %v, want 3.5`, got)
+	}
+	if got := c.Value("leave"); got != 0 {
+		t.Errorf(`Value("leave") = %v, want 0`, got)
+	}
+}
+
+func TestLabelCountChecked(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("events_total", "Events.", "event")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with the wrong number of label values did not panic")
+		}
+	}()
+	c.Inc("join", "extra")
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{1.5, "1.5"},
+		{1e21, "1e+21"},
+		{0.0001, "0.0001"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v); got != tt.want {
+			t.Errorf("formatValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
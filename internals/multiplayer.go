@@ -56,6 +56,17 @@ func generatePlayerID() string {
 //   - Time limit (3-10 minutes)
 //   - Number of questions (10-25)
 //   - Game type (must not be empty)
+//   - Round time for synchronized rooms (5-60 seconds, defaults to 20)
+//   - Teams (none, or 2-4 unique names)
+//   - Elimination rules for "ELIMINATION" rooms (see validateEliminationRules)
+//   - Answer tolerance (0-3 edits)
+//   - Question types (see validateQuestionTypes), multiple choice rooms only
+//   - Flag variant (see validateFlagVariant), defaulting by difficulty (see
+//     resolveFlagVariant)
+//   - Difficulty (see validateDifficulty)
+//   - Question pack (see validatePack)
+//   - Region (see validateRegion)
+//   - Map retries (see validateMapRetries)
 func ValidateCreateRoomRequest(req *game.CreateRoomRequest) error {
 	if req.TimeLimit < 3 || req.TimeLimit > 10 {
 		return errors.New("time limit must be between 3 and 10 minutes")
@@ -66,6 +77,50 @@ func ValidateCreateRoomRequest(req *game.CreateRoomRequest) error {
 	if req.GameType == "" {
 		return errors.New("game type is required")
 	}
+	if req.GameType == "ELIMINATION" {
+		if err := validateEliminationRules(req); err != nil {
+			return err
+		}
+	}
+	if req.Synchronized {
+		if req.RoundTime == 0 {
+			req.RoundTime = defaultRoundTime
+		}
+		if req.RoundTime < 5 || req.RoundTime > 60 {
+			return errors.New("round time must be between 5 and 60 seconds")
+		}
+		if err := validateRoundsFit(req); err != nil {
+			return err
+		}
+	}
+	if err := validateTeams(req.Teams); err != nil {
+		return err
+	}
+	if req.Tolerance < 0 || req.Tolerance > 3 {
+		return errors.New("answer tolerance must be between 0 and 3")
+	}
+	if len(req.QuestionTypes) > 0 && (req.GameType == "MAP" || req.GameType == "REVERSE" || req.GameType == "TYPED") {
+		return errors.New("question types can only be mixed in multiple choice rooms")
+	}
+	if err := validateQuestionTypes(req.QuestionTypes); err != nil {
+		return err
+	}
+	if err := validateFlagVariant(req.FlagVariant); err != nil {
+		return err
+	}
+	if err := validateDifficulty(req.Difficulty); err != nil {
+		return err
+	}
+	req.FlagVariant = resolveFlagVariant(req.FlagVariant, req.Difficulty)
+	if err := validatePack(req.PackID, req.Region, req.NumQuestions); err != nil {
+		return err
+	}
+	if err := validateRegion(req.Region, req.NumQuestions); err != nil {
+		return err
+	}
+	if err := validateMapRetries(req); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -77,7 +132,8 @@ func ValidateCreateRoomRequest(req *game.CreateRoomRequest) error {
 // Content-Type: application/json
 //
 // Request Body:
-//   - CreateRoomRequest struct with additional HostUsername field
+//   - CreateRoomRequest struct with additional HostUsername field, replaced
+//     by the account name when the host is logged in (see resolveUsername)
 //
 // Response:
 //   - 200: Room created successfully with room details
@@ -113,14 +169,16 @@ func createRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(rooms) >= 10 {
+	hostUsername, err := resolveUsername(r, req.HostUsername)
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusForbidden)
+		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Maximum number of rooms (10) reached. Cannot create more rooms.",
+			Error: err.Error(),
 		})
 		return
 	}
+	req.HostUsername = hostUsername
 
 	if req.HostUsername == "" {
 		w.Header().Set("Content-Type", "application/json")
@@ -131,7 +189,7 @@ func createRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	questions, err := generateQuestions(req.NumQuestions, req.GameType)
+	questions, err := generateQuestions(req.NumQuestions, req.GameType, req.QuestionTypes, req.Difficulty, req.PackID, req.Region)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -141,32 +199,93 @@ func createRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roomID := generateRoomID()
+	bounds, err := regionBounds(req.Region)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Failed to locate the region: " + err.Error(),
+		})
+		return
+	}
+	applyFlagVariant(questions, req.FlagVariant)
+
 	room := &game.Room{
-		Code:      roomID,
-		Hostname:  req.HostUsername,
-		Players:   make(map[*websocket.Conn]*game.Player),
-		Questions: make(map[string]*game.Question),
-		Start:     false,
-		TimeLimit: req.TimeLimit,
-		GameMode:  req.GameType,
+		Hostname:   req.HostUsername,
+		Players:    make(map[*websocket.Conn]*game.Player),
+		Spectators: make(map[*websocket.Conn]*game.Player),
+		Questions:  make(map[string]*game.Question),
+		Start:      false,
+		TimeLimit:  req.TimeLimit,
+		GameMode:   req.GameType,
+		Teams:      req.Teams,
+		Tolerance:  req.Tolerance,
+
+		QuestionTypes: req.QuestionTypes,
+		FlagVariant:   req.FlagVariant,
+		Difficulty:    req.Difficulty,
+		PackID:        req.PackID,
+		Region:        req.Region,
+		Bounds:        bounds,
+		MapRetries:    req.MapRetries,
+
+		Synchronized: req.Synchronized,
+		RoundTime:    req.RoundTime,
+
+		Lives:                req.Lives,
+		EliminationsPerRound: req.EliminationsPerRound,
+
+		JuryFinale: req.JuryFinale,
 	}
 
 	for i, q := range questions {
 		room.Questions[strconv.Itoa(i)] = &q
 	}
 
-	rooms[roomID] = room
+	mu.Lock()
+	// Checked along with adding the room, so rooms created at the same
+	// time can't go over the limit
+	if len(rooms) >= 10 {
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(ErrorResponse{
+			Error: "Maximum number of rooms (10) reached. Cannot create more rooms.",
+		})
+		return
+	}
+	// Codes are random, so draw again while one is taken by an open room
+	room.Code = generateRoomID()
+	for rooms[room.Code] != nil {
+		room.Code = generateRoomID()
+	}
+	room.Audit = openAudit(room)
+	rooms[room.Code] = room
+	requestLogger(r).Info("Room created", "room", room.Code, "host", room.Hostname, "gamemode", room.GameMode)
 
 	response := map[string]interface{}{
-		"code":         room.Code,
-		"host":         room.Hostname,
-		"players":      getSerializablePlayers(room),
-		"start":        room.Start,
-		"timeLimit":    room.TimeLimit,
-		"numQuestions": len(questions),
-		"gamemode":     room.GameMode,
+		"code":          room.Code,
+		"host":          room.Hostname,
+		"players":       getSerializablePlayers(room),
+		"start":         room.Start,
+		"timeLimit":     room.TimeLimit,
+		"numQuestions":  len(questions),
+		"gamemode":      room.GameMode,
+		"synchronized":  room.Synchronized,
+		"roundTime":     room.RoundTime,
+		"teams":         room.Teams,
+		"questionTypes": room.QuestionTypes,
+		"flagVariant":   room.FlagVariant,
+		"difficulty":    room.Difficulty,
+		"packId":        room.PackID,
+		"region":        room.Region,
+		"bbox":          room.Bounds,
+		"mapRetries":    room.MapRetries,
+		"juryFinale":    room.JuryFinale,
 	}
+	mu.Unlock()
+	auditMessage(room, "", map[string]interface{}{"event": "room_created", "data": response})
+	auditMessage(room, "", map[string]interface{}{"event": "questions", "data": questions})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -180,14 +299,19 @@ func createRoomHandler(w http.ResponseWriter, r *http.Request) {
 // Content-Type: application/json
 //
 // Request Body:
-//   - Username: Player's desired username (4-20 characters)
+//   - Username: Player's desired username (4-20 characters), ignored when
+//     the player is logged in (see resolveUsername)
 //   - RoomID: Target room identifier
+//   - Spectator: Optional, join as a spectator. Spectators skip the
+//     started, capacity, username length and unique username checks; a
+//     guest still can't watch under the name of an account.
 //
 // Response:
-//   - 200: Successfully joined room with room details
+//   - 200: Successfully joined room with room details and the username
+//     to play under
 //   - 400: Invalid request parameters
 //   - 404: Room not found
-//   - 409: Username conflict
+//   - 409: Username conflict, or the username belongs to an account
 //   - 403: Room is full
 //   - 401: Game has started in this room
 func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
@@ -197,8 +321,9 @@ func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Username string `json:"username"`
-		RoomID   string `json:"roomID"`
+		Username  string `json:"username"`
+		RoomID    string `json:"roomID"`
+		Spectator bool   `json:"spectator"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -208,8 +333,17 @@ func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate username
-	if req.Username == "" || len(req.Username) < 4 || len(req.Username) > 20 {
+	username, err := resolveUsername(r, req.Username)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+	req.Username = username
+
+	// Validate username, spectators may go without one
+	if !req.Spectator && (len(req.Username) < 4 || len(req.Username) > 20) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Username must be between 4 and 20 characters"})
@@ -224,7 +358,9 @@ func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mu.Lock()
 	room, exists := rooms[req.RoomID]
+	mu.Unlock()
 
 	if !exists {
 		w.Header().Set("Content-Type", "application/json")
@@ -233,28 +369,45 @@ func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if room.Start {
+	mu.Lock()
+	started, full := room.Start, len(room.Players) >= 9
+	usernameExists := false
+	for _, player := range room.Players {
+		if player != nil && strings.EqualFold(player.Username, req.Username) {
+			usernameExists = true
+			break
+		}
+	}
+	response := map[string]interface{}{
+		"code":         room.Code,
+		"host":         room.Hostname,
+		"players":      getSerializablePlayers(room),
+		"timeLimit":    room.TimeLimit,
+		"numQuestions": len(room.Questions),
+	}
+	mu.Unlock()
+
+	if req.Spectator {
+		response["spectator"] = true
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if started {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Game has already started. You cannot join now."})
 		return
 	}
 
-	if len(room.Players) >= 9 {
+	if full {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Room is full, only 9 members can join in one room"})
 		return
 	}
 
-	usernameExists := false
-	for _, player := range room.Players {
-		if player != nil && strings.EqualFold(player.Username, req.Username) {
-			usernameExists = true
-			break
-		}
-	}
-
 	if usernameExists {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusConflict)
@@ -264,13 +417,7 @@ func joinRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := map[string]interface{}{
-		"code":         room.Code,
-		"host":         room.Hostname,
-		"players":      getSerializablePlayers(room),
-		"timeLimit":    room.TimeLimit,
-		"numQuestions": len(room.Questions),
-	}
+	response["username"] = req.Username
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -290,7 +437,9 @@ func getRoomHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomID := vars["id"]
 
+	mu.Lock()
 	room, exists := rooms[roomID]
+	mu.Unlock()
 
 	if !exists {
 		w.Header().Set("Content-Type", "application/json")
@@ -299,20 +448,35 @@ func getRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mu.Lock()
 	response := map[string]interface{}{
-		"code":         room.Code,
-		"host":         room.Hostname,
-		"players":      getSerializablePlayers(room),
-		"timeLimit":    room.TimeLimit,
-		"numQuestions": len(room.Questions),
-		"gamemode":     room.GameMode,
+		"code":          room.Code,
+		"host":          room.Hostname,
+		"players":       getSerializablePlayers(room),
+		"timeLimit":     room.TimeLimit,
+		"numQuestions":  len(room.Questions),
+		"gamemode":      room.GameMode,
+		"spectators":    len(room.Spectators),
+		"synchronized":  room.Synchronized,
+		"roundTime":     room.RoundTime,
+		"teams":         teamStandings(room),
+		"questionTypes": room.QuestionTypes,
+		"flagVariant":   room.FlagVariant,
+		"difficulty":    room.Difficulty,
+		"packId":        room.PackID,
+		"region":        room.Region,
+		"bbox":          room.Bounds,
+		"mapRetries":    room.MapRetries,
+		"juryFinale":    room.JuryFinale,
 	}
+	mu.Unlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// adminHandler returns the info about the total games currently operating in the server
+// adminHandler returns the info about the total games currently operating in the server.
+// Room codes are enough to join any game, so only admins may list them (see requireAdmin).
 func adminHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		w.Header().Set("Content-Type", "application/json")
@@ -320,16 +484,13 @@ func adminHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(ErrorResponse{Error: "Method not allowed"})
 		return
 	}
-
-	if len(rooms) == 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "No room found"})
+	if _, ok := requireAdmin(w, r); !ok {
 		return
 	}
 
 	// Create a response structure to hold all room details
 	var allRooms []map[string]interface{}
+	mu.Lock()
 	for _, room := range rooms {
 		roomDetails := map[string]interface{}{
 			"code":         room.Code,
@@ -338,9 +499,19 @@ func adminHandler(w http.ResponseWriter, r *http.Request) {
 			"numQuestions": len(room.Questions),
 			"gameStarted":  room.Start,
 			"players":      getSerializablePlayers(room),
+			"spectators":   len(room.Spectators),
+			"gamemode":     room.GameMode,
 		}
 		allRooms = append(allRooms, roomDetails)
 	}
+	mu.Unlock()
+
+	if len(allRooms) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "No room found"})
+		return
+	}
 
 	// Respond with the JSON of all rooms
 	w.Header().Set("Content-Type", "application/json")
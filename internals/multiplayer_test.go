@@ -0,0 +1,50 @@
+package internals
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestJoinRoomSpectator(t *testing.T) {
+	room := &game.Room{
+		Code:       "T026",
+		Hostname:   "alice",
+		Players:    map[*websocket.Conn]*game.Player{{}: {ID: "1", Username: "alice"}},
+		Spectators: make(map[*websocket.Conn]*game.Player),
+		Questions:  map[string]*game.Question{"0": {Type: "MCQ", Code: "FR"}},
+		Start:      true,
+	}
+	mu.Lock()
+	rooms[room.Code] = room
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(rooms, room.Code)
+		mu.Unlock()
+	})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"spectator without a name", `{"roomID": "T026", "spectator": true}`, http.StatusOK},
+		{"spectator with a short name", `{"username": "al", "roomID": "T026", "spectator": true}`, http.StatusOK},
+		{"spectator under a player's name", `{"username": "alice", "roomID": "T026", "spectator": true}`, http.StatusOK},
+		{"player with a short name", `{"username": "al", "roomID": "T026"}`, http.StatusBadRequest},
+		{"player after the start", `{"username": "bobby", "roomID": "T026"}`, http.StatusUnauthorized},
+		{"spectator of no room", `{"roomID": "T000", "spectator": true}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		joinRoomHandler(w, httptest.NewRequest(http.MethodPost, "/api/joinroom", strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+}
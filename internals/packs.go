@@ -0,0 +1,283 @@
+package internals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/packs"
+	"github.com/gorilla/mux"
+)
+
+const (
+	packsFile = "./data/packs.json"
+
+	// maxPackSize caps the countries of a pack.
+	maxPackSize = 250
+)
+
+var (
+	questionPacks *packs.Store
+	packsOnce     sync.Once
+)
+
+// loadPacks reads the question packs from data/packs.json on first use and
+// returns the cached store afterwards.
+func loadPacks() *packs.Store {
+	packsOnce.Do(func() {
+		var err error
+		questionPacks, err = packs.Load(packsFile)
+		if err != nil {
+			slog.Error("Failed to load question packs", "error", err)
+			questionPacks = packs.New()
+		}
+	})
+	return questionPacks
+}
+
+// validatePack checks the question pack asked for a game of numQuestions
+// questions: each question asks about another country, so the pack needs
+// at least as many countries of the catalog, counting only those in region
+// when the game is played on one (see validateRegion). Empty draws from the
+// whole catalog.
+func validatePack(id, region string, numQuestions int) error {
+	if id == "" {
+		return nil
+	}
+	pack, ok := loadPacks().Get(id)
+	if !ok {
+		return errors.New("question pack not found")
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		return err
+	}
+	pool := packCountries(pack, countries)
+	where := fmt.Sprintf("question pack %q", pack.Name)
+	if region != "" {
+		codes, err := regionCodes(region, countries)
+		if err != nil {
+			return err
+		}
+		pool = inRegion(pool, codes)
+		where += fmt.Sprintf(" and region %q", region)
+	}
+	if len(pool) == 0 {
+		return fmt.Errorf("no countries to ask about in %s", where)
+	}
+	if len(pool) < numQuestions {
+		return fmt.Errorf("countries to ask about in %s: %d, fewer than the %d questions", where, len(pool), numQuestions)
+	}
+	return nil
+}
+
+// packCountries returns the countries of a pack, in the pack's order.
+// Countries removed from the catalog since the pack was made are skipped.
+func packCountries(pack packs.Pack, countries *catalog.Catalog) []catalog.Country {
+	var selected []catalog.Country
+	for _, code := range pack.Codes {
+		if country, ok := countries.ByCode(code); ok {
+			selected = append(selected, country)
+		}
+	}
+	return selected
+}
+
+// validatePackCodes checks that every code is a country of the catalog with a
+// flag, and returns the codes in upper case.
+func validatePackCodes(codes []string, countries *catalog.Catalog) ([]string, error) {
+	if len(codes) == 0 {
+		return nil, errors.New("the pack has no countries")
+	}
+	if len(codes) > maxPackSize {
+		return nil, fmt.Errorf("a pack can have at most %d countries", maxPackSize)
+	}
+
+	seen := make(map[string]bool)
+	valid := make([]string, 0, len(codes))
+	for _, code := range codes {
+		country, ok := countries.ByCode(strings.TrimSpace(code))
+		if !ok {
+			return nil, fmt.Errorf("unknown country code %q", code)
+		}
+		if !flagExists(country.Code) {
+			return nil, fmt.Errorf("no flag for country code %q", code)
+		}
+		if seen[country.Code] {
+			return nil, fmt.Errorf("country code %q is listed twice", code)
+		}
+		seen[country.Code] = true
+		valid = append(valid, country.Code)
+	}
+	return valid, nil
+}
+
+// createPackHandler stores a custom question pack that rooms can then be
+// created with (see CreateRoomRequest.PackID). Only logged in players can
+// make packs, up to packs.MaxPacksPerOwner each. The countries are given in
+// one of three ways: a list of codes, questions are drawn from at random; a
+// region, all the countries of a continent or named region; or an ordered
+// list of questions, asked in that order. Instead of JSON, a CSV of codes
+// (first column, one country per row) can be uploaded with the name and
+// order in the query.
+//
+// HTTP Method: POST
+// Content-Type: application/json or text/csv
+//
+// Request Body (JSON):
+//   - name: 1-40 characters
+//   - codes: ISO codes of the countries to draw from
+//   - region: A continent or named region, e.g. "Europe" or "Balkans",
+//     instead of codes (see regionMembers)
+//   - questions: ISO codes of the countries to ask about, in order, instead
+//     of codes
+//
+// Query Parameters (CSV):
+//   - name: 1-40 characters
+//   - ordered: "true" to ask the questions in the order of the rows
+//
+// Response:
+//   - 200: The stored pack, with its ID
+//   - 400: Invalid pack, e.g. a code without a country or flag
+//   - 401: Not logged in
+//   - 403: The account already made too many packs
+//   - 503: Too many packs stored
+func createPackHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+
+	var req struct {
+		Name      string   `json:"name"`
+		Codes     []string `json:"codes"`
+		Region    string   `json:"region"`
+		Questions []string `json:"questions"`
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pack := packs.Pack{}
+	if contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); contentType == "text/csv" {
+		req.Name = r.URL.Query().Get("name")
+		pack.Ordered = r.URL.Query().Get("ordered") == "true"
+		req.Codes, err = packs.ReadCSV(r.Body)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&req)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid pack format"})
+		return
+	}
+
+	var problem string
+	pack.Name = strings.TrimSpace(req.Name)
+	switch given := len(req.Codes) + len(req.Questions); {
+	case pack.Name == "" || utf8.RuneCountInString(pack.Name) > 40:
+		problem = "Pack name must be between 1 and 40 characters"
+	case (given > 0) == (req.Region != "") || (len(req.Codes) > 0 && len(req.Questions) > 0):
+		problem = "Give exactly one of codes, region or questions"
+	case req.Region != "":
+		req.Codes, err = regionCodes(req.Region, countries)
+		if err != nil {
+			problem = err.Error()
+		} else if len(req.Codes) == 0 {
+			problem = "No countries in region " + req.Region
+		}
+	case len(req.Questions) > 0:
+		req.Codes = req.Questions
+		pack.Ordered = true
+	}
+	if problem == "" {
+		pack.Codes, err = validatePackCodes(req.Codes, countries)
+		if err != nil {
+			problem = err.Error()
+		}
+	}
+	if problem != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: problem})
+		return
+	}
+
+	pack.Owner = account.Username
+
+	pack, err = loadPacks().Add(pack)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, packs.ErrOwnerFull):
+			status = http.StatusForbidden
+		case errors.Is(err, packs.ErrFull):
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pack)
+}
+
+// listPacksHandler lists the question packs, newest first.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: [{"id", "name", "owner", "size", "ordered"}]
+func listPacksHandler(w http.ResponseWriter, r *http.Request) {
+	list := []map[string]interface{}{}
+	for _, pack := range loadPacks().List() {
+		list = append(list, map[string]interface{}{
+			"id":      pack.ID,
+			"name":    pack.Name,
+			"owner":   pack.Owner,
+			"size":    len(pack.Codes),
+			"ordered": pack.Ordered,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// packHandler returns a question pack with its countries.
+//
+// HTTP Method: GET
+// URL Parameters:
+//   - id: The pack ID
+//
+// Response:
+//   - 200: The pack
+//   - 404: No such pack
+func packHandler(w http.ResponseWriter, r *http.Request) {
+	pack, ok := loadPacks().Get(mux.Vars(r)["id"])
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Question pack not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pack)
+}
@@ -0,0 +1,180 @@
+// Package packs stores custom question packs: named lists of countries hosts
+// define for themed games, such as "Balkans only" or "flags with eagles".
+package packs
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/jsonfile"
+)
+
+const (
+	// MaxPacks is the number of packs the store keeps.
+	MaxPacks = 500
+	// MaxPacksPerOwner is the number of packs one account can make.
+	MaxPacksPerOwner = 20
+)
+
+var (
+	// ErrFull is returned by Add when the store holds MaxPacks packs.
+	ErrFull = errors.New("too many question packs")
+	// ErrOwnerFull is returned by Add when the owner of the pack already
+	// made MaxPacksPerOwner packs.
+	ErrOwnerFull = errors.New("you already made too many question packs")
+)
+
+// Pack is a custom question pack.
+type Pack struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Owner   string    `json:"owner"`   // account that created the pack
+	Codes   []string  `json:"codes"`   // ISO codes of the countries asked about
+	Ordered bool      `json:"ordered"` // questions follow the order of Codes
+	Created time.Time `json:"created"`
+}
+
+// Store holds the packs by ID. It is safe for concurrent use.
+type Store struct {
+	mu    sync.Mutex
+	packs map[string]*Pack
+	dirty bool
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{packs: make(map[string]*Pack)}
+}
+
+// Load reads a store saved by Save. A missing file gives an empty store.
+func Load(file string) (*Store, error) {
+	s := New()
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.packs); err != nil {
+		return nil, err
+	}
+	if s.packs == nil {
+		s.packs = make(map[string]*Pack)
+	}
+	return s, nil
+}
+
+// Save writes the store to file if it changed since the last save. The file
+// is replaced atomically.
+func (s *Store) Save(file string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	if err := jsonfile.Write(file, s.packs, 0o644); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Add stores a pack under a new ID and returns it. The codes must have been
+// validated by the caller.
+func (s *Store) Add(pack Pack) (Pack, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.packs) >= MaxPacks {
+		return Pack{}, ErrFull
+	}
+	owned := 0
+	for _, stored := range s.packs {
+		if stored.Owner == pack.Owner {
+			owned++
+		}
+	}
+	if owned >= MaxPacksPerOwner {
+		return Pack{}, ErrOwnerFull
+	}
+
+	// IDs are random, so draw again while one is taken
+	pack.ID = ""
+	for pack.ID == "" || s.packs[pack.ID] != nil {
+		buf := make([]byte, 4)
+		if _, err := rand.Read(buf); err != nil {
+			return Pack{}, err
+		}
+		pack.ID = hex.EncodeToString(buf)
+	}
+	pack.Codes = append([]string(nil), pack.Codes...)
+	pack.Created = time.Now()
+	s.packs[pack.ID] = &pack
+	s.dirty = true
+	return pack, nil
+}
+
+// Get returns the pack with the given ID.
+func (s *Store) Get(id string) (Pack, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pack, ok := s.packs[id]
+	if !ok {
+		return Pack{}, false
+	}
+	return *pack, true
+}
+
+// List returns every pack, newest first.
+func (s *Store) List() []Pack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Pack, 0, len(s.packs))
+	for _, pack := range s.packs {
+		list = append(list, *pack)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.After(list[j].Created)
+	})
+	return list
+}
+
+// ReadCSV reads the country codes of a pack from a CSV upload: the first
+// column of every row, in order. Blank rows and a "code" header are skipped.
+func ReadCSV(r io.Reader) ([]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var codes []string
+	for i := 0; ; i++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return codes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.TrimSpace(row[0])
+		if code == "" || (i == 0 && strings.EqualFold(code, "code")) {
+			continue
+		}
+		codes = append(codes, code)
+	}
+}
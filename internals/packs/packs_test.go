@@ -0,0 +1,100 @@
+package packs
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"one code per row", "FR\nDE\nIT\n", []string{"FR", "DE", "IT"}},
+		{"header skipped", "code,name\nFR,France\nDE,Germany\n", []string{"FR", "DE"}},
+		{"header in any case", "Code\nFR\n", []string{"FR"}},
+		{"header only on the first row", "FR\ncode\n", []string{"FR", "code"}},
+		{"blank rows and spaces", "FR\n\n  DE  ,x\n,\n", []string{"FR", "DE"}},
+		{"ragged rows", "FR,France,Paris\nDE\n", []string{"FR", "DE"}},
+		{"windows line endings", "FR\r\nDE\r\n", []string{"FR", "DE"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := ReadCSV(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%s: ReadCSV error: %v", tt.name, err)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: ReadCSV = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ReadCSV(strings.NewReader("\"FR\nDE")); err == nil {
+		t.Error("ReadCSV of an unterminated quote succeeded")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	s := New()
+
+	ids := make(map[string]bool)
+	for i := 0; i < MaxPacksPerOwner; i++ {
+		pack, err := s.Add(Pack{ID: "taken", Name: "Pack " + strconv.Itoa(i), Owner: "alice", Codes: []string{"FR"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pack.ID) != 8 || ids[pack.ID] {
+			t.Fatalf("pack got ID %q, want a new 8 digit one", pack.ID)
+		}
+		ids[pack.ID] = true
+	}
+
+	if _, err := s.Add(Pack{Name: "One too many", Owner: "alice"}); !errors.Is(err, ErrOwnerFull) {
+		t.Errorf("Add over the owner limit: %v, want ErrOwnerFull", err)
+	}
+	if _, err := s.Add(Pack{Name: "Other owner", Owner: "bob"}); err != nil {
+		t.Errorf("Add for another owner: %v", err)
+	}
+	if len(s.List()) != MaxPacksPerOwner+1 {
+		t.Errorf("store lists %d packs, want %d", len(s.List()), MaxPacksPerOwner+1)
+	}
+}
+
+func TestAddCopiesCodes(t *testing.T) {
+	s := New()
+	codes := []string{"FR", "DE"}
+	pack, err := s.Add(Pack{Name: "Neighbours", Owner: "alice", Codes: codes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes[0] = "XX"
+
+	stored, ok := s.Get(pack.ID)
+	if !ok || stored.Codes[0] != "FR" {
+		t.Errorf("stored pack = %+v, want its own copy of the codes", stored)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "packs.json")
+
+	s := New()
+	pack, _ := s.Add(Pack{Name: "Balkans", Owner: "alice", Codes: []string{"HR", "RS"}, Ordered: true})
+	if err := s.Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := loaded.Get(pack.ID)
+	if !ok || got.Name != "Balkans" || !got.Ordered || len(got.Codes) != 2 {
+		t.Errorf("loaded pack = %+v, %v", got, ok)
+	}
+}
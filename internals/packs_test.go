@@ -0,0 +1,96 @@
+package internals
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adimail/fun-with-flags/internals/packs"
+)
+
+// chdirRoot runs the test from the root of the repository, where the data
+// and flag paths are relative to.
+func chdirRoot(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestValidatePackCodes(t *testing.T) {
+	chdirRoot(t)
+	countries, err := loadCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := validatePackCodes([]string{"fr", " de ", "IT"}, countries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(codes, ",") != "FR,DE,IT" {
+		t.Errorf("validatePackCodes = %v, want [FR DE IT]", codes)
+	}
+
+	tests := []struct {
+		name  string
+		codes []string
+		want  string
+	}{
+		{"no codes", nil, "no countries"},
+		{"unknown code", []string{"FR", "ZZ"}, `unknown country code "ZZ"`},
+		{"listed twice", []string{"FR", "fr"}, `"fr" is listed twice`},
+		{"path in code", []string{"../FR"}, "unknown country code"},
+		{"too many", make([]string, maxPackSize+1), "at most"},
+	}
+	for _, tt := range tests {
+		_, err := validatePackCodes(tt.codes, countries)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestValidatePack(t *testing.T) {
+	chdirRoot(t)
+	store := packs.New()
+	packsOnce.Do(func() {})
+	saved := questionPacks
+	questionPacks = store
+	t.Cleanup(func() { questionPacks = saved })
+
+	small, _ := store.Add(packs.Pack{Name: "Small", Codes: []string{"FR", "DE", "IT"}})
+	mixed, _ := store.Add(packs.Pack{Name: "Mixed", Codes: []string{
+		"FR", "DE", "IT", "ES", "PT", "NL", "AT", "CH", "PL", "DK", "NO", "SE",
+	}})
+
+	tests := []struct {
+		name         string
+		id, region   string
+		numQuestions int
+		want         string // empty for no error
+	}{
+		{"no pack", "", "", 10, ""},
+		{"unknown pack", "missing", "", 10, "not found"},
+		{"big enough", small.ID, "", 3, ""},
+		{"too small", small.ID, "", 10, `question pack "Small": 3, fewer than the 10 questions`},
+		{"big enough in the continent", mixed.ID, "europe", 12, ""},
+		{"big enough in the region", mixed.ID, "nordics", 3, ""},
+		{"region cuts it down", mixed.ID, "nordics", 10, `and region "nordics": 3, fewer`},
+		{"nothing in the region", small.ID, "balkans", 1, "no countries to ask about"},
+		{"unknown region", small.ID, "atlantis", 1, "unknown region"},
+	}
+	for _, tt := range tests {
+		err := validatePack(tt.id, tt.region, tt.numQuestions)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package internals
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/adimail/fun-with-flags/internals/practice"
+)
+
+const (
+	practiceFile = "./data/practice.json"
+
+	// newFlagsPerSession caps the flags a learner has never seen in one
+	// batch of practice questions.
+	newFlagsPerSession = 5
+)
+
+var (
+	learnerProgress *practice.Progress
+	practiceOnce    sync.Once
+)
+
+// loadPractice reads the practice progress from data/practice.json on first
+// use and returns the cached progress afterwards.
+func loadPractice() *practice.Progress {
+	practiceOnce.Do(func() {
+		var err error
+		learnerProgress, err = practice.Load(practiceFile)
+		if err != nil {
+			slog.Error("Failed to load practice progress", "error", err)
+			learnerProgress = practice.New()
+		}
+	})
+	return learnerProgress
+}
+
+// practiceNextHandler returns the next flags the logged in player should
+// practice: the flags due for review, most overdue first, then a few flags
+// the player has never seen, easiest first (see stats.CountryStats.Difficulty).
+//
+// HTTP Method: GET
+// Query Parameters:
+//   - count: Number of questions (1-25, defaults to 10)
+//   - type: Question type, "MCQ" (default), "MAP", "REVERSE" or "TYPED"
+//
+// Response:
+//   - 200: {"questions", "due", "new", "next_due"}; next_due is the time the
+//     next flag not due yet comes due, null if none
+//   - 400: Invalid request parameters
+//   - 401: Not logged in
+func practiceNextHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+	learner := account.Username
+
+	query := r.URL.Query()
+
+	count := 10
+	if value := query.Get("count"); value != "" {
+		var err error
+		count, err = strconv.Atoi(value)
+		if err != nil || count < 1 || count > 25 {
+			http.Error(w, "Invalid number of questions", http.StatusBadRequest)
+			return
+		}
+	}
+
+	questionType := query.Get("type")
+	switch questionType {
+	case "":
+		questionType = "MCQ"
+	case "MCQ", "MAP", "REVERSE", "TYPED":
+	default:
+		http.Error(w, "Invalid question type", http.StatusBadRequest)
+		return
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	progress := loadPractice()
+	cards := progress.Cards(learner)
+
+	var selected []catalog.Country
+	seen := make(map[string]bool)
+	var nextDue *time.Time
+	for _, card := range cards {
+		seen[card.Code] = true
+		if card.Due.After(now) {
+			// Cards are sorted by due date, the first one ahead is next
+			if nextDue == nil {
+				due := card.Due
+				nextDue = &due
+			}
+			continue
+		}
+		if len(selected) >= count {
+			continue
+		}
+		if country, ok := countries.ByCode(card.Code); ok {
+			selected = append(selected, country)
+		}
+	}
+	due := len(selected)
+
+	var unseen []catalog.Country
+	for _, country := range countries.Countries {
+		if !seen[country.Code] {
+			unseen = append(unseen, country)
+		}
+	}
+	answers := loadStats()
+	sort.SliceStable(unseen, func(i, j int) bool {
+		return answers.Difficulty(unseen[i].Code) < answers.Difficulty(unseen[j].Code)
+	})
+	for _, country := range unseen {
+		if len(selected) >= count || len(selected)-due >= newFlagsPerSession {
+			break
+		}
+		selected = append(selected, country)
+	}
+
+	rng := newRandomGenerator()
+	questions := []game.Question{}
+	for _, country := range selected {
+		questions = append(questions, generateQuestion(country, countries.Countries, []string{questionType}, rng))
+	}
+	questionsServed.Add(float64(len(questions)), questionType)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"questions": questions,
+		"due":       due,
+		"new":       len(selected) - due,
+		"next_due":  nextDue,
+	})
+}
+
+// practiceAnswerHandler records the logged in player's answer to a practice
+// question and schedules the next review of that flag (see
+// practice.Card.Review).
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - type: Question type, as in the question
+//   - code: ISO code of the country asked about
+//   - correct: Whether the answer was right
+//   - near_miss: Whether a wrong typed answer was close
+//   - elapsed_ms: Time taken to answer, in milliseconds
+//
+// Response:
+//   - 200: The player's updated card for the flag
+//   - 400: Invalid request parameters
+//   - 401: Not logged in
+//   - 404: Unknown country code
+func practiceAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Type      string `json:"type"`
+		Code      string `json:"code"`
+		Correct   bool   `json:"correct"`
+		NearMiss  bool   `json:"near_miss"`
+		ElapsedMs int64  `json:"elapsed_ms"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+
+	if req.ElapsedMs < 0 || req.ElapsedMs > time.Hour.Milliseconds() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid answer time"})
+		return
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	country, ok := countries.ByCode(req.Code)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Country not found"})
+		return
+	}
+
+	elapsed := time.Duration(req.ElapsedMs) * time.Millisecond
+	quality := practice.Quality(req.Correct, req.NearMiss, elapsed)
+	card := loadPractice().Record(account.Username, country.Code, quality, time.Now())
+	recordAnswerStats(&game.Question{Type: req.Type, Code: country.Code}, req.Correct, elapsed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(card)
+}
@@ -0,0 +1,192 @@
+// Package practice schedules flag reviews for the practice mode with the
+// SM-2 spaced repetition algorithm, and keeps every learner's progress.
+package practice
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/jsonfile"
+)
+
+const (
+	defaultEase = 2.5
+	minEase     = 1.3
+	day         = 24 * time.Hour
+
+	// relearnDelay is how soon a forgotten flag comes back.
+	relearnDelay = 10 * time.Minute
+)
+
+// Card is a learner's progress on one flag.
+type Card struct {
+	Code         string    `json:"code"`
+	Repetitions  int       `json:"repetitions"` // successful reviews in a row
+	IntervalDays int       `json:"interval_days"`
+	Ease         float64   `json:"ease"`
+	Due          time.Time `json:"due"`
+	LastReviewed time.Time `json:"last_reviewed"`
+	Reviews      int       `json:"reviews"`
+	Lapses       int       `json:"lapses"` // times the flag was forgotten
+}
+
+// Quality grades an answer on the SM-2 scale from 0 to 5. Wrong answers
+// grade 1, or 2 when they were a near miss; right answers grade 5 when
+// quick, 4 when hesitant and 3 when slow.
+func Quality(correct, nearMiss bool, elapsed time.Duration) int {
+	switch {
+	case !correct && nearMiss:
+		return 2
+	case !correct:
+		return 1
+	case elapsed < 5*time.Second:
+		return 5
+	case elapsed < 10*time.Second:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// Review updates the card after an answer of the given quality (0-5), as in
+// SM-2: a quality below 3 starts the repetitions over and brings the flag
+// back soon; otherwise the interval grows from 1 day to 6 days and then by
+// the ease factor, which itself follows the quality of the answers.
+func (c *Card) Review(quality int, now time.Time) {
+	if c.Ease == 0 {
+		c.Ease = defaultEase
+	}
+
+	c.Reviews++
+	c.LastReviewed = now
+
+	if quality < 3 {
+		if c.Repetitions > 0 {
+			c.Lapses++
+		}
+		c.Repetitions = 0
+		c.IntervalDays = 0
+		c.Due = now.Add(relearnDelay)
+	} else {
+		switch c.Repetitions {
+		case 0:
+			c.IntervalDays = 1
+		case 1:
+			c.IntervalDays = 6
+		default:
+			c.IntervalDays = int(float64(c.IntervalDays)*c.Ease + 0.5)
+		}
+		c.Repetitions++
+		c.Due = now.Add(time.Duration(c.IntervalDays) * day)
+	}
+
+	q := float64(5 - quality)
+	c.Ease += 0.1 - q*(0.08+q*0.02)
+	if c.Ease < minEase {
+		c.Ease = minEase
+	}
+}
+
+// Progress holds the cards of every learner, keyed by learner ID and then
+// by country code. It is safe for concurrent use.
+type Progress struct {
+	mu       sync.Mutex
+	learners map[string]map[string]*Card
+	dirty    bool
+}
+
+// New returns empty progress.
+func New() *Progress {
+	return &Progress{learners: make(map[string]map[string]*Card)}
+}
+
+// Load reads progress saved by Save. A missing file gives empty progress.
+func Load(file string) (*Progress, error) {
+	p := New()
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &p.learners); err != nil {
+		return nil, err
+	}
+	if p.learners == nil {
+		p.learners = make(map[string]map[string]*Card)
+	}
+	return p, nil
+}
+
+// Save writes the progress to file if it changed since the last save. The
+// file is replaced atomically.
+func (p *Progress) Save(file string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	if err := jsonfile.Write(file, p.learners, 0o644); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// Record reviews the learner's card for a flag, creating it on the first
+// answer, and returns a copy of the updated card.
+func (p *Progress) Record(learner, code string, quality int, now time.Time) Card {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cards, ok := p.learners[learner]
+	if !ok {
+		cards = make(map[string]*Card)
+		p.learners[learner] = cards
+	}
+	card, ok := cards[code]
+	if !ok {
+		card = &Card{Code: code}
+		cards[code] = card
+	}
+
+	card.Review(quality, now)
+	p.dirty = true
+	return *card
+}
+
+// Cards returns a copy of the learner's cards, ordered by due date.
+func (p *Progress) Cards(learner string) []Card {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cards := []Card{}
+	for _, card := range p.learners[learner] {
+		cards = append(cards, *card)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].Due.Before(cards[j].Due)
+	})
+	return cards
+}
+
+// Due returns the codes of up to limit flags the learner should review at
+// now, most overdue first.
+func (p *Progress) Due(learner string, now time.Time, limit int) []string {
+	var codes []string
+	for _, card := range p.Cards(learner) {
+		if len(codes) >= limit || card.Due.After(now) {
+			break
+		}
+		codes = append(codes, card.Code)
+	}
+	return codes
+}
@@ -0,0 +1,100 @@
+package practice
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		correct  bool
+		nearMiss bool
+		elapsed  time.Duration
+		want     int
+	}{
+		{"wrong", false, false, time.Second, 1},
+		{"near miss", false, true, time.Second, 2},
+		{"quick", true, false, 4 * time.Second, 5},
+		{"hesitant", true, false, 5 * time.Second, 4},
+		{"slow", true, false, 10 * time.Second, 3},
+		{"near miss ignored when right", true, true, time.Second, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Quality(tt.correct, tt.nearMiss, tt.elapsed); got != tt.want {
+				t.Errorf("Quality(%v, %v, %v) = %d, want %d", tt.correct, tt.nearMiss, tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCardReview(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		qualities   []int
+		repetitions int
+		interval    int
+		ease        float64
+		due         time.Duration // after now
+		lapses      int
+	}{
+		{"first right answer", []int{5}, 1, 1, 2.6, day, 0},
+		{"second right answer", []int{5, 5}, 2, 6, 2.7, 6 * day, 0},
+		{"interval grows by the ease", []int{5, 5, 5}, 3, 16, 2.8, 16 * day, 0},
+		{"hesitant answer keeps the ease", []int{4}, 1, 1, 2.5, day, 0},
+		{"slow answer lowers the ease", []int{3}, 1, 1, 2.36, day, 0},
+		{"wrong answer on a new card", []int{1}, 0, 0, 1.96, relearnDelay, 0},
+		{"near miss", []int{2}, 0, 0, 2.18, relearnDelay, 0},
+		{"forgotten card lapses", []int{5, 5, 1}, 0, 0, 2.16, relearnDelay, 1},
+		{"relearned card starts over", []int{5, 5, 1, 5}, 1, 1, 2.26, day, 1},
+		{"ease has a floor", []int{0, 0, 0}, 0, 0, minEase, relearnDelay, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := &Card{Code: "FR"}
+			for _, quality := range tt.qualities {
+				card.Review(quality, now)
+			}
+
+			if card.Repetitions != tt.repetitions {
+				t.Errorf("Repetitions = %d, want %d", card.Repetitions, tt.repetitions)
+			}
+			if card.IntervalDays != tt.interval {
+				t.Errorf("IntervalDays = %d, want %d", card.IntervalDays, tt.interval)
+			}
+			if math.Abs(card.Ease-tt.ease) > 1e-9 {
+				t.Errorf("Ease = %v, want %v", card.Ease, tt.ease)
+			}
+			if want := now.Add(tt.due); !card.Due.Equal(want) {
+				t.Errorf("Due = %v, want %v", card.Due, want)
+			}
+			if card.Lapses != tt.lapses {
+				t.Errorf("Lapses = %d, want %d", card.Lapses, tt.lapses)
+			}
+			if card.Reviews != len(tt.qualities) {
+				t.Errorf("Reviews = %d, want %d", card.Reviews, len(tt.qualities))
+			}
+		})
+	}
+}
+
+func TestProgressDue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := New()
+	p.Record("alice", "FR", 1, now.Add(-time.Hour)) // due 50 minutes ago
+	p.Record("alice", "DE", 5, now)                 // due tomorrow
+	p.Record("alice", "IT", 1, now.Add(-2*time.Hour))
+	p.Record("bob", "ES", 1, now.Add(-time.Hour))
+
+	got := p.Due("alice", now, 10)
+	if len(got) != 2 || got[0] != "IT" || got[1] != "FR" {
+		t.Errorf("Due = %v, want [IT FR]", got)
+	}
+	if got := p.Due("alice", now, 1); len(got) != 1 || got[0] != "IT" {
+		t.Errorf("Due with limit 1 = %v, want [IT]", got)
+	}
+}
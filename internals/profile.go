@@ -0,0 +1,446 @@
+package internals
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adimail/fun-with-flags/internals/achievements"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/adimail/fun-with-flags/internals/profiles"
+	"github.com/gorilla/mux"
+)
+
+const profilesFile = "./data/profiles.json"
+
+var (
+	profileStore *profiles.Store
+	profilesOnce sync.Once
+)
+
+// loadProfiles reads the profiles from data/profiles.json on first use and
+// returns the cached store afterwards.
+func loadProfiles() *profiles.Store {
+	profilesOnce.Do(func() {
+		var err error
+		profileStore, err = profiles.Load(profilesFile)
+		if err != nil {
+			slog.Error("Failed to load profiles", "error", err)
+			profileStore = profiles.New()
+		}
+	})
+	return profileStore
+}
+
+// gameResult collects what a player did in the room for their profile. The
+// caller must hold mu.
+func gameResult(room *game.Room, player *game.Player) profiles.GameResult {
+	result := profiles.GameResult{
+		Room:     room.Code,
+		Mode:     room.GameMode,
+		Score:    player.Score,
+		Answers:  []profiles.Answer{},
+		Finished: time.Now(),
+	}
+	for _, answer := range player.Answers {
+		question, ok := room.Questions[strconv.Itoa(answer.QuestionIndex)]
+		if !ok {
+			continue
+		}
+		result.Answers = append(result.Answers, profiles.Answer{
+			Mode:    question.Type,
+			Code:    question.Code,
+			Correct: answer.Correct,
+		})
+	}
+	return result
+}
+
+// recordGameResults saves the results of a room that just ended, once. The
+// results of logged in players go straight to their profile and may earn
+// them badges (see awardBadges). Guests get a "game_result" event with a
+// claim code that adds the result to an account they log in to later (see
+// claimHandler). Rooms with a jury finale record their results once the
+// finale has decided the winner.
+func recordGameResults(room *game.Room) {
+	mu.Lock()
+	if room.ResultsRecorded {
+		mu.Unlock()
+		return
+	}
+	room.ResultsRecorded = true
+	gamesFinished.Inc()
+	players := roomParticipants(room)
+	won := make(map[*game.Player]bool)
+	for _, winner := range roomWinners(room) {
+		won[winner] = true
+	}
+	// Players still connected may be answering, so the results are taken
+	// under the lock
+	results := make(map[*game.Player]profiles.GameResult)
+	for _, player := range players {
+		result := gameResult(room, player)
+		if len(result.Answers) == 0 {
+			continue
+		}
+		result.Players = len(players)
+		result.Won = won[player]
+		results[player] = result
+	}
+	mu.Unlock()
+
+	store := loadProfiles()
+	for _, player := range players {
+		result, ok := results[player]
+		if !ok {
+			continue
+		}
+
+		data := map[string]interface{}{
+			"score":   result.Score,
+			"answers": len(result.Answers),
+		}
+		if player.Account != "" {
+			store.Record(player.Account, result)
+		} else {
+			code, err := store.Hold(result)
+			if err != nil {
+				slog.Error("Failed to hold guest result", "room", room.Code, "player", player.ID, "error", err)
+				continue
+			}
+			data["claim_code"] = code
+		}
+
+		message := map[string]interface{}{
+			"event": "game_result",
+			"data":  data,
+		}
+		auditMessage(room, player.ID, message)
+		if err := writeJSON(player.Conn, message); err != nil {
+			slog.Warn("Error sending game result to player", "room", room.Code, "player", player.ID, "error", err)
+		}
+
+		awardBadges(room, player, achievements.Event{
+			Type:    achievements.GameEvent,
+			Won:     result.Won,
+			Players: result.Players,
+		})
+	}
+}
+
+// profileResponse is the JSON view of a profile, with its statistics
+// computed.
+func profileResponse(p profiles.Profile) map[string]interface{} {
+	modes := map[string]interface{}{}
+	total := profiles.Tally{}
+	for mode, tally := range p.Modes {
+		modes[mode] = map[string]interface{}{
+			"answers":  tally.Answers,
+			"correct":  tally.Correct,
+			"accuracy": tally.Accuracy(),
+		}
+		total.Answers += tally.Answers
+		total.Correct += tally.Correct
+	}
+
+	badges := []map[string]interface{}{}
+	engine := loadBadges()
+	for id, awarded := range p.Badges {
+		badge, ok := engine.Badge(id)
+		if !ok {
+			continue
+		}
+		badges = append(badges, map[string]interface{}{
+			"id":          badge.ID,
+			"name":        badge.Name,
+			"description": badge.Description,
+			"icon":        badge.Icon,
+			"awarded_at":  awarded,
+		})
+	}
+	sort.Slice(badges, func(i, j int) bool {
+		return badges[i]["awarded_at"].(time.Time).Before(badges[j]["awarded_at"].(time.Time))
+	})
+
+	return map[string]interface{}{
+		"username":       p.Username,
+		"display_name":   p.DisplayName,
+		"avatar":         p.Avatar,
+		"country":        p.Country,
+		"games_played":   p.GamesPlayed,
+		"games_won":      p.GamesWon,
+		"answers":        total.Answers,
+		"accuracy":       total.Accuracy(),
+		"modes":          modes,
+		"best_streak":    p.BestStreak,
+		"day_streak":     p.DayStreak,
+		"badges":         badges,
+		"favourites":     p.Favourites(),
+		"most_missed":    p.MostMissed(),
+		"claimed_guests": p.ClaimedGuests,
+	}
+}
+
+// myProfileHandler returns the profile of the logged in player.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: The profile (see profileResponse)
+//   - 401: Not logged in
+func myProfileHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileResponse(loadProfiles().Get(account.Username)))
+}
+
+// profileHandler returns the profile of any account. Profiles hold the
+// history of a player's games, so only logged in players can see them.
+//
+// HTTP Method: GET
+// URL Parameters:
+//   - username: The account username, any case
+//
+// Response:
+//   - 200: The profile (see profileResponse)
+//   - 401: Not logged in
+//   - 404: No such account
+func profileHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAccount(w, r); !ok {
+		return
+	}
+
+	account, ok := loadAccounts().Lookup(mux.Vars(r)["username"])
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Player not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileResponse(loadProfiles().Get(account.Username)))
+}
+
+// historyHandler returns the recent games of the logged in player, newest
+// first, up to profiles.HistorySize of them.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: {"games"}, each {"room", "mode", "score", "answers", "correct",
+//     "players", "won", "finished"}
+//   - 401: Not logged in
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	history := loadProfiles().Get(account.Username).History
+	games := make([]map[string]interface{}, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		result := history[i]
+		correct := 0
+		for _, answer := range result.Answers {
+			if answer.Correct {
+				correct++
+			}
+		}
+		games = append(games, map[string]interface{}{
+			"room":     result.Room,
+			"mode":     result.Mode,
+			"score":    result.Score,
+			"answers":  len(result.Answers),
+			"correct":  correct,
+			"players":  result.Players,
+			"won":      result.Won,
+			"finished": result.Finished,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"games": games})
+}
+
+// leaderboardHandler ranks the players with an account by games won, then
+// accuracy, then games played. Only logged in players can see it, like the
+// profiles it is built from.
+//
+// HTTP Method: GET
+// Query Parameters:
+//   - limit: Number of players listed, 1-100 (defaults to 20)
+//
+// Response:
+//   - 200: {"players"}, each {"rank", "username", "display_name", "avatar",
+//     "country", "games_played", "games_won", "accuracy"}
+//   - 400: Invalid limit
+//   - 401: Not logged in
+func leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := requireAccount(w, r); !ok {
+		return
+	}
+
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	players := []map[string]interface{}{}
+	for i, p := range loadProfiles().Leaderboard(limit) {
+		players = append(players, map[string]interface{}{
+			"rank":         i + 1,
+			"username":     p.Username,
+			"display_name": p.DisplayName,
+			"avatar":       p.Avatar,
+			"country":      p.Country,
+			"games_played": p.GamesPlayed,
+			"games_won":    p.GamesWon,
+			"accuracy":     p.Total().Accuracy(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"players": players})
+}
+
+// updateProfileHandler changes how the logged in player presents themselves.
+// Fields left empty are unchanged.
+//
+// HTTP Method: PUT
+// Content-Type: application/json
+//
+// Request Body:
+//   - display_name: 1-30 characters
+//   - avatar: A single emoji
+//   - country: ISO alpha-2 code of the player's country
+//
+// Response:
+//   - 200: The updated profile (see profileResponse)
+//   - 400: Invalid request parameters
+//   - 401: Not logged in
+func updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		DisplayName string `json:"display_name"`
+		Avatar      string `json:"avatar"`
+		Country     string `json:"country"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+
+	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	req.Avatar = strings.TrimSpace(req.Avatar)
+	req.Country = strings.ToUpper(strings.TrimSpace(req.Country))
+
+	var problem string
+	switch {
+	case utf8.RuneCountInString(req.DisplayName) > 30:
+		problem = "Display name must be at most 30 characters"
+	case req.Avatar != "" && !isEmoji(req.Avatar):
+		problem = "Avatar must be a single emoji"
+	case req.Country != "" && !flagExists(req.Country):
+		problem = "Unknown country code"
+	}
+	if problem != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: problem})
+		return
+	}
+
+	profile := loadProfiles().Update(account.Username, req.DisplayName, req.Avatar, req.Country)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileResponse(profile))
+}
+
+// isEmoji loosely checks that s is one emoji: a short string without
+// letters, digits, spaces or punctuation. Emoji made of several code points
+// (skin tones, flags, ZWJ sequences) are accepted.
+func isEmoji(s string) bool {
+	if len(s) > 32 || utf8.RuneCountInString(s) > 8 {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// claimHandler adds guest game results to the profile of the logged in
+// player. Guests receive a claim code for every game they finish (see
+// recordGameResults); the browser keeps them until the player logs in.
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - codes: The claim codes
+//
+// Response:
+//   - 200: {"claimed", "badges"}, the number of results added and the badges
+//     they earned; unknown, expired and already claimed codes are skipped
+//   - 400: Invalid request parameters
+//   - 401: Not logged in
+func claimHandler(w http.ResponseWriter, r *http.Request) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Codes []string `json:"codes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Codes) > 100 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid claim codes"})
+		return
+	}
+
+	claimed := loadProfiles().Claim(account.Username, req.Codes)
+
+	badges := []achievements.Badge{}
+	for _, result := range claimed {
+		badges = append(badges, checkBadges(account.Username, achievements.Event{
+			Type:    achievements.GameEvent,
+			Won:     result.Won,
+			Players: result.Players,
+		})...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"claimed": len(claimed),
+		"badges":  badges,
+	})
+}
@@ -0,0 +1,469 @@
+// Package profiles keeps the persistent profile of every account: how the
+// player presents themselves and their statistics across games. It also
+// holds the results of guests until they claim them into an account.
+package profiles
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/jsonfile"
+)
+
+// ClaimTTL is how long a guest has to claim a game result.
+const ClaimTTL = 7 * 24 * time.Hour
+
+// topFlags is the number of favourite and most missed flags listed.
+const topFlags = 3
+
+// HistorySize is the number of recent games kept in a profile.
+const HistorySize = 50
+
+// Answer is one answer given in a game.
+type Answer struct {
+	Mode    string `json:"mode"` // question type, e.g. "MCQ"
+	Code    string `json:"code"` // ISO code of the country asked about
+	Correct bool   `json:"correct"`
+}
+
+// GameResult is what a player did in one game.
+type GameResult struct {
+	Room     string    `json:"room"`
+	Mode     string    `json:"mode"` // game mode of the room
+	Score    int       `json:"score"`
+	Answers  []Answer  `json:"answers"`
+	Finished time.Time `json:"finished"`
+	Players  int       `json:"players"` // number of players in the room
+	Won      bool      `json:"won"`
+}
+
+// Tally counts answers and how many of them were right.
+type Tally struct {
+	Answers int `json:"answers"`
+	Correct int `json:"correct"`
+}
+
+// Accuracy returns the share of correct answers, from 0 to 100.
+func (t Tally) Accuracy() float64 {
+	if t.Answers == 0 {
+		return 0
+	}
+	return 100 * float64(t.Correct) / float64(t.Answers)
+}
+
+// Profile is the persistent profile of an account.
+type Profile struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Avatar      string `json:"avatar"`  // an emoji
+	Country     string `json:"country"` // ISO code of the player's country
+
+	GamesPlayed   int               `json:"games_played"`
+	GamesWon      int               `json:"games_won"`
+	Modes         map[string]*Tally `json:"modes"` // keyed by question type
+	Flags         map[string]*Tally `json:"flags"` // flag questions, by ISO code
+	Streak        int               `json:"streak"`
+	BestStreak    int               `json:"best_streak"`
+	DayStreak     int               `json:"day_streak"` // days played in a row
+	LastPlayed    time.Time         `json:"last_played"`
+	ClaimedGuests int               `json:"claimed_guests"` // guest games claimed
+
+	Badges map[string]time.Time `json:"badges"` // award time by badge ID
+
+	// The last HistorySize games, oldest first
+	History []GameResult `json:"history"`
+}
+
+// Total returns the answers of the player across question types.
+func (p *Profile) Total() Tally {
+	total := Tally{}
+	for _, tally := range p.Modes {
+		total.Answers += tally.Answers
+		total.Correct += tally.Correct
+	}
+	return total
+}
+
+// Favourites returns the flags the player got right most often.
+func (p *Profile) Favourites() []string {
+	return p.rankFlags(func(t *Tally) int { return t.Correct })
+}
+
+// MostMissed returns the flags the player got wrong most often.
+func (p *Profile) MostMissed() []string {
+	return p.rankFlags(func(t *Tally) int { return t.Answers - t.Correct })
+}
+
+func (p *Profile) rankFlags(count func(*Tally) int) []string {
+	codes := []string{}
+	for code, tally := range p.Flags {
+		if count(tally) > 0 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		ci, cj := count(p.Flags[codes[i]]), count(p.Flags[codes[j]])
+		if ci != cj {
+			return ci > cj
+		}
+		return codes[i] < codes[j]
+	})
+	if len(codes) > topFlags {
+		codes = codes[:topFlags]
+	}
+	return codes
+}
+
+// apply adds a game result to the profile.
+func (p *Profile) apply(result GameResult) {
+	p.GamesPlayed++
+	if result.Won {
+		p.GamesWon++
+	}
+	p.History = append(p.History, result)
+	if len(p.History) > HistorySize {
+		p.History = append([]GameResult(nil), p.History[len(p.History)-HistorySize:]...)
+	}
+	if result.Finished.After(p.LastPlayed) {
+		last, finished := p.LastPlayed.Local(), result.Finished.Local()
+		switch {
+		case sameDay(last, finished) && p.DayStreak > 0:
+			// already played that day
+		case sameDay(last, finished.AddDate(0, 0, -1)):
+			p.DayStreak++
+		default:
+			p.DayStreak = 1
+		}
+		p.LastPlayed = result.Finished
+	}
+
+	for _, answer := range result.Answers {
+		if p.Modes[answer.Mode] == nil {
+			p.Modes[answer.Mode] = &Tally{}
+		}
+		tallies := []*Tally{p.Modes[answer.Mode]}
+		// Trivia questions (capitals, neighbours...) don't show the flag,
+		// so they only count toward their question type
+		switch answer.Mode {
+		case "MCQ", "MAP", "REVERSE", "TYPED":
+			if p.Flags[answer.Code] == nil {
+				p.Flags[answer.Code] = &Tally{}
+			}
+			tallies = append(tallies, p.Flags[answer.Code])
+		}
+		for _, tally := range tallies {
+			tally.Answers++
+			if answer.Correct {
+				tally.Correct++
+			}
+		}
+
+		if answer.Correct {
+			p.Streak++
+			if p.Streak > p.BestStreak {
+				p.BestStreak = p.Streak
+			}
+		} else {
+			p.Streak = 0
+		}
+	}
+}
+
+// sameDay reports whether a and b fall on the same calendar day.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// copy returns a deep copy of the profile.
+func (p *Profile) copy() Profile {
+	c := *p
+	c.Modes = make(map[string]*Tally, len(p.Modes))
+	for mode, tally := range p.Modes {
+		t := *tally
+		c.Modes[mode] = &t
+	}
+	c.Flags = make(map[string]*Tally, len(p.Flags))
+	for code, tally := range p.Flags {
+		t := *tally
+		c.Flags[code] = &t
+	}
+	c.Badges = make(map[string]time.Time, len(p.Badges))
+	for id, awarded := range p.Badges {
+		c.Badges[id] = awarded
+	}
+	c.History = make([]GameResult, len(p.History))
+	for i, result := range p.History {
+		c.History[i] = result
+		c.History[i].Answers = append([]Answer(nil), result.Answers...)
+	}
+	return c
+}
+
+type claim struct {
+	Result  GameResult `json:"result"`
+	Expires time.Time  `json:"expires"`
+}
+
+// Store holds the profiles, keyed by lower case username, and the guest
+// results waiting to be claimed, keyed by claim code. It is safe for
+// concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	profiles map[string]*Profile
+	claims   map[string]*claim
+	dirty    bool
+}
+
+// storeFile is the layout of the file written by Save.
+type storeFile struct {
+	Profiles map[string]*Profile `json:"profiles"`
+	Claims   map[string]*claim   `json:"claims"`
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{
+		profiles: make(map[string]*Profile),
+		claims:   make(map[string]*claim),
+	}
+}
+
+// Load reads a store saved by Save. A missing file gives an empty store.
+func Load(file string) (*Store, error) {
+	s := New()
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved storeFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	if saved.Profiles != nil {
+		s.profiles = saved.Profiles
+	}
+	if saved.Claims != nil {
+		s.claims = saved.Claims
+	}
+	return s, nil
+}
+
+// Save writes the store to file if it changed since the last save, dropping
+// expired claims. The file is replaced atomically.
+func (s *Store) Save(file string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	now := time.Now()
+	for code, c := range s.claims {
+		if now.After(c.Expires) {
+			delete(s.claims, code)
+		}
+	}
+	if err := jsonfile.Write(file, storeFile{s.profiles, s.claims}, 0o644); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// profile returns the profile of username, creating it if needed. The
+// caller must hold s.mu.
+func (s *Store) profile(username string) *Profile {
+	key := strings.ToLower(username)
+	p, ok := s.profiles[key]
+	if !ok {
+		p = &Profile{
+			Username:    username,
+			DisplayName: username,
+			Modes:       make(map[string]*Tally),
+			Flags:       make(map[string]*Tally),
+			Badges:      make(map[string]time.Time),
+		}
+		s.profiles[key] = p
+	}
+	if p.Modes == nil {
+		p.Modes = make(map[string]*Tally)
+	}
+	if p.Flags == nil {
+		p.Flags = make(map[string]*Tally)
+	}
+	if p.Badges == nil {
+		p.Badges = make(map[string]time.Time)
+	}
+	return p
+}
+
+// Get returns a copy of the profile of username. Accounts that never played
+// get an empty profile.
+func (s *Store) Get(username string) Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(username)
+	if p, ok := s.profiles[key]; ok {
+		return p.copy()
+	}
+	return Profile{
+		Username:    username,
+		DisplayName: username,
+		Modes:       map[string]*Tally{},
+		Flags:       map[string]*Tally{},
+		Badges:      map[string]time.Time{},
+	}
+}
+
+// Leaderboard returns copies of the n best profiles of the players who
+// finished a game: most games won first, then best accuracy, then most
+// games played.
+func (s *Store) Leaderboard(n int) []Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type entry struct {
+		profile  *Profile
+		accuracy float64
+	}
+	var entries []entry
+	for _, p := range s.profiles {
+		if p.GamesPlayed == 0 {
+			continue
+		}
+		entries = append(entries, entry{p, p.Total().Accuracy()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.profile.GamesWon != b.profile.GamesWon:
+			return a.profile.GamesWon > b.profile.GamesWon
+		case a.accuracy != b.accuracy:
+			return a.accuracy > b.accuracy
+		case a.profile.GamesPlayed != b.profile.GamesPlayed:
+			return a.profile.GamesPlayed > b.profile.GamesPlayed
+		}
+		return strings.ToLower(a.profile.Username) < strings.ToLower(b.profile.Username)
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+
+	leaders := make([]Profile, len(entries))
+	for i, e := range entries {
+		leaders[i] = e.profile.copy()
+	}
+	return leaders
+}
+
+// Update sets how the player presents themselves. Empty values are left
+// unchanged.
+func (s *Store) Update(username, displayName, avatar, country string) Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.profile(username)
+	if displayName != "" {
+		p.DisplayName = displayName
+	}
+	if avatar != "" {
+		p.Avatar = avatar
+	}
+	if country != "" {
+		p.Country = country
+	}
+	s.dirty = true
+	return p.copy()
+}
+
+// Record adds a game result to the profile of username.
+func (s *Store) Record(username string, result GameResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.profile(username).apply(result)
+	s.dirty = true
+}
+
+// Award gives the badges to username and returns those they didn't hold
+// already.
+func (s *Store) Award(username string, ids []string, now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.profile(username)
+	var awarded []string
+	for _, id := range ids {
+		if _, held := p.Badges[id]; held {
+			continue
+		}
+		p.Badges[id] = now
+		awarded = append(awarded, id)
+		s.dirty = true
+	}
+	return awarded
+}
+
+// Hold keeps the result of a guest for ClaimTTL and returns the code that
+// claims it.
+func (s *Store) Hold(result GameResult) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.claims[code] = &claim{
+		Result:  result,
+		Expires: time.Now().Add(ClaimTTL),
+	}
+	s.dirty = true
+	return code, nil
+}
+
+// Claim adds the guest results held under the codes to the profile of
+// username. Unknown and expired codes are skipped; each code can only be
+// claimed once. It returns the results claimed.
+func (s *Store) Claim(username string, codes []string) []GameResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	claimed := []GameResult{}
+	for _, code := range codes {
+		c, ok := s.claims[code]
+		if !ok {
+			continue
+		}
+		delete(s.claims, code)
+		s.dirty = true
+		if now.After(c.Expires) {
+			continue
+		}
+
+		p := s.profile(username)
+		p.apply(c.Result)
+		p.ClaimedGuests++
+		claimed = append(claimed, c.Result)
+	}
+	return claimed
+}
@@ -0,0 +1,240 @@
+package profiles
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// day returns noon of the given day of January 2024, local time.
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 12, 0, 0, 0, time.Local)
+}
+
+func TestApplyDayStreak(t *testing.T) {
+	tests := []struct {
+		name  string
+		days  []int // days the games finished, in the order recorded
+		want  int
+		games int
+	}{
+		{"first game", []int{1}, 1, 1},
+		{"same day", []int{1, 1, 1}, 1, 3},
+		{"consecutive days", []int{1, 2, 3}, 3, 3},
+		{"a day missed", []int{1, 2, 4}, 1, 3},
+		{"twice a day", []int{1, 1, 2, 2, 3}, 3, 5},
+		{"older result claimed late", []int{2, 3, 1}, 2, 3},
+	}
+	for _, tt := range tests {
+		s := New()
+		for _, d := range tt.days {
+			s.Record("alice", GameResult{Finished: day(d)})
+		}
+		p := s.Get("alice")
+		if p.DayStreak != tt.want || p.GamesPlayed != tt.games {
+			t.Errorf("%s: day streak %d after %d games, want %d after %d", tt.name, p.DayStreak, p.GamesPlayed, tt.want, tt.games)
+		}
+	}
+}
+
+func TestApplyAnswers(t *testing.T) {
+	s := New()
+	s.Record("alice", GameResult{Finished: day(1), Answers: []Answer{
+		{Mode: "MCQ", Code: "FR", Correct: true},
+		{Mode: "MCQ", Code: "DE", Correct: true},
+		{Mode: "MAP", Code: "FR", Correct: true},
+		{Mode: "MAP", Code: "IT", Correct: false},
+		{Mode: "MCQ", Code: "FR", Correct: true},
+	}})
+	// The streak carries over to the next game
+	s.Record("Alice", GameResult{Finished: day(2), Answers: []Answer{
+		{Mode: "MCQ", Code: "ES", Correct: true},
+		{Mode: "MCQ", Code: "IT", Correct: true},
+	}})
+
+	p := s.Get("ALICE")
+	if p.Streak != 3 || p.BestStreak != 3 {
+		t.Errorf("streak %d, best %d, want 3 and 3", p.Streak, p.BestStreak)
+	}
+	if mcq := p.Modes["MCQ"]; mcq == nil || mcq.Answers != 5 || mcq.Correct != 5 {
+		t.Errorf("MCQ tally = %+v, want 5 of 5", mcq)
+	}
+	if mp := p.Modes["MAP"]; mp == nil || mp.Answers != 2 || mp.Correct != 1 || mp.Accuracy() != 50 {
+		t.Errorf("MAP tally = %+v, want 1 of 2", mp)
+	}
+	if fr := p.Flags["FR"]; fr == nil || fr.Correct != 3 {
+		t.Errorf("FR tally = %+v, want 3 correct", fr)
+	}
+	if got := p.Favourites(); len(got) != 3 || got[0] != "FR" {
+		t.Errorf("favourites = %v, want FR first", got)
+	}
+	if got := p.MostMissed(); len(got) != 1 || got[0] != "IT" {
+		t.Errorf("most missed = %v, want [IT]", got)
+	}
+
+	// Get returns a copy
+	p.Flags["FR"].Correct = 100
+	if s.Get("alice").Flags["FR"].Correct != 3 {
+		t.Error("changing the profile returned by Get changed the store")
+	}
+}
+
+func TestClaim(t *testing.T) {
+	s := New()
+	result := GameResult{Finished: time.Now(), Answers: []Answer{{Mode: "MCQ", Code: "FR", Correct: true}}}
+	code, err := s.Hold(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.Hold(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.claims[expired].Expires = time.Now().Add(-time.Minute)
+
+	claimed := s.Claim("alice", []string{code, expired, "unknown"})
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d results, want 1", len(claimed))
+	}
+	p := s.Get("alice")
+	if p.GamesPlayed != 1 || p.ClaimedGuests != 1 {
+		t.Errorf("profile has %d games, %d claimed, want 1 and 1", p.GamesPlayed, p.ClaimedGuests)
+	}
+
+	// Codes are claimed once, expired ones are forgotten
+	if claimed := s.Claim("bob", []string{code, expired}); len(claimed) != 0 {
+		t.Errorf("claimed %d results again, want none", len(claimed))
+	}
+	if len(s.claims) != 0 {
+		t.Errorf("%d claims left, want none", len(s.claims))
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profiles.json")
+
+	s := New()
+	s.Record("alice", GameResult{Finished: day(1), Answers: []Answer{{Mode: "MCQ", Code: "FR", Correct: true}}})
+	s.Update("alice", "Alice", "🦊", "FR")
+	s.Award("alice", []string{"streak-10"}, day(1))
+	kept, _ := s.Hold(GameResult{Finished: day(1)})
+	dropped, _ := s.Hold(GameResult{Finished: day(1)})
+	s.claims[dropped].Expires = time.Now().Add(-time.Minute)
+	if err := s.Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := loaded.Get("alice")
+	if p.DisplayName != "Alice" || p.Avatar != "🦊" || p.Country != "FR" || p.GamesPlayed != 1 {
+		t.Errorf("loaded profile = %+v", p)
+	}
+	if _, ok := p.Badges["streak-10"]; !ok {
+		t.Error("loaded profile lost its badge")
+	}
+	if _, ok := loaded.claims[kept]; !ok {
+		t.Error("claim lost on save")
+	}
+	if _, ok := loaded.claims[dropped]; ok {
+		t.Error("expired claim saved")
+	}
+
+	if awarded := loaded.Award("alice", []string{"streak-10", "eurovision"}, day(2)); len(awarded) != 1 || awarded[0] != "eurovision" {
+		t.Errorf("Award = %v, want only the new badge", awarded)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := s.Get("nobody"); p.GamesPlayed != 0 || p.DisplayName != "nobody" {
+		t.Errorf("profile from an empty store = %+v", p)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := New()
+	for i := 0; i < HistorySize+5; i++ {
+		s.Record("alice", GameResult{Score: i, Finished: day(1), Won: i%2 == 0,
+			Answers: []Answer{{Mode: "MCQ", Code: "FR", Correct: true}}})
+	}
+
+	p := s.Get("alice")
+	if len(p.History) != HistorySize {
+		t.Fatalf("history of %d games, want %d", len(p.History), HistorySize)
+	}
+	if p.History[0].Score != 5 || p.History[HistorySize-1].Score != HistorySize+4 {
+		t.Errorf("history holds scores %d to %d, want the last games, oldest first",
+			p.History[0].Score, p.History[HistorySize-1].Score)
+	}
+	if p.GamesWon != (HistorySize+6)/2 {
+		t.Errorf("%d games won, want %d", p.GamesWon, (HistorySize+6)/2)
+	}
+
+	// Get returns a copy
+	p.History[0].Answers[0].Correct = false
+	if !s.Get("alice").History[0].Answers[0].Correct {
+		t.Error("changing the history returned by Get changed the store")
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	s := New()
+	record := func(username string, won bool, correct ...bool) {
+		result := GameResult{Finished: day(1), Won: won}
+		for _, c := range correct {
+			result.Answers = append(result.Answers, Answer{Mode: "MCQ", Code: "FR", Correct: c})
+		}
+		s.Record(username, result)
+	}
+	record("dora", false, true, true)
+	record("carl", true, true, false)
+	record("bea", true, true, true)
+	record("abe", true, true, true)
+	record("abe", false, true, true)
+	record("erin", true, false, false)
+	record("erin", true, false, false)
+	s.Update("ghost", "Ghost", "", "") // never played
+
+	var got []string
+	for _, p := range s.Leaderboard(10) {
+		got = append(got, p.Username)
+	}
+	// Most wins, then accuracy, then games played
+	want := "erin abe bea carl dora"
+	if strings.Join(got, " ") != want {
+		t.Errorf("leaderboard = %v, want %s", got, want)
+	}
+	if top := s.Leaderboard(2); len(top) != 2 || top[1].Username != "abe" {
+		t.Errorf("Leaderboard(2) = %v", top)
+	}
+}
+
+func TestApplyTriviaAnswers(t *testing.T) {
+	s := New()
+	s.Record("alice", GameResult{Finished: day(1), Answers: []Answer{
+		{Mode: "CAPITAL", Code: "FR", Correct: true},
+		{Mode: "NEIGHBOUR", Code: "DE", Correct: false},
+		{Mode: "TYPED", Code: "FR", Correct: true},
+	}})
+
+	p := s.Get("alice")
+	if fr := p.Flags["FR"]; fr == nil || fr.Answers != 1 {
+		t.Errorf("FR flag tally = %+v, want only the typed answer", fr)
+	}
+	if _, ok := p.Flags["DE"]; ok {
+		t.Error("neighbour question counted as a DE flag")
+	}
+	if capital := p.Modes["CAPITAL"]; capital == nil || capital.Correct != 1 {
+		t.Errorf("CAPITAL tally = %+v, want 1 correct", capital)
+	}
+	if p.Streak != 1 {
+		t.Errorf("streak %d, want 1, trivia answers still count", p.Streak)
+	}
+}
@@ -0,0 +1,253 @@
+package internals
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+// questionGenerator builds a question about country from the catalog data,
+// with the wrong options taken from the countries of options (see
+// optionPool). It returns false when there isn't what it needs to ask about
+// that country, e.g. a neighbour question for an island.
+type questionGenerator func(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool)
+
+// questionGenerators maps each question type to its generator. The game
+// types "MCQ", "MAP", "REVERSE" and "TYPED" each have a generator of the same
+// name; the others are trivia types that rooms can mix together.
+var questionGenerators = map[string]questionGenerator{
+	"MCQ":        flagQuestion,
+	"MAP":        mapQuestion,
+	"REVERSE":    reverseQuestion,
+	"TYPED":      typedQuestion,
+	"CAPITAL":    capitalQuestion,
+	"CONTINENT":  continentQuestion,
+	"NEIGHBOUR":  neighbourQuestion,
+	"EUROVISION": eurovisionQuestion,
+}
+
+// mixableQuestionTypes are the question types a room can mix: all of them
+// show a flag with four text options, so they play the same way as "MCQ".
+var mixableQuestionTypes = []string{"MCQ", "CAPITAL", "CONTINENT", "NEIGHBOUR", "EUROVISION"}
+
+// continents are the options of continent questions.
+var continents = []string{"Africa", "Asia", "Europe", "North America", "Oceania", "South America"}
+
+// validateQuestionTypes checks that every requested question type can be
+// mixed into a room.
+func validateQuestionTypes(types []string) error {
+	for _, t := range types {
+		if !containsString(mixableQuestionTypes, t) {
+			return fmt.Errorf("unknown question type %q, expected one of %v", t, mixableQuestionTypes)
+		}
+	}
+	return nil
+}
+
+// questionTypeFor returns the question type played by default in a game type.
+func questionTypeFor(gameType string) string {
+	if _, ok := questionGenerators[gameType]; ok {
+		return gameType
+	}
+	return "MCQ"
+}
+
+// minOptionPool is the fewest countries a pack or region needs for the wrong
+// options of its questions to be drawn from it rather than from the whole
+// catalog.
+const minOptionPool = 8
+
+// optionPool returns the countries the wrong options of questions drawn from
+// pool are taken from: pool itself when it is large enough, so that a
+// "Balkans" game offers Balkan countries to pick from, and the whole catalog
+// otherwise.
+func optionPool(pool []catalog.Country, countries *catalog.Catalog) []catalog.Country {
+	if len(pool) >= minOptionPool {
+		return pool
+	}
+	return countries.Countries
+}
+
+// generateQuestion builds a question about country using one of types,
+// chosen at random, with the wrong options taken from options. Types that
+// can't be backed for this country are skipped, falling back to a flag
+// question if none of them fit.
+func generateQuestion(country catalog.Country, options []catalog.Country, types []string, rng *rand.Rand) game.Question {
+	for _, i := range rng.Perm(len(types)) {
+		if question, ok := questionGenerators[types[i]](country, options, rng); ok {
+			return question
+		}
+	}
+
+	question, _ := flagQuestion(country, options, rng)
+	return question
+}
+
+// optionsQuestion builds a multiple choice question with the right answer
+// and three distractors, shuffled.
+func optionsQuestion(questionType string, country catalog.Country, answer string, distractors []string, rng *rand.Rand) game.Question {
+	options := append([]string{answer}, distractors...)
+	shuffleOptions(options, rng)
+
+	return game.Question{
+		Type:    questionType,
+		Code:    country.Code,
+		FlagURL: country.FlagURL(),
+		Options: options,
+		Answer:  answer,
+	}
+}
+
+// flagQuestion shows the flag with four country names to pick from.
+func flagQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	var distractors []string
+	for _, other := range pickDistractors(options, country, 3, rng) {
+		distractors = append(distractors, other.Name)
+	}
+	return optionsQuestion("MCQ", country, country.Name, distractors, rng), true
+}
+
+// mapQuestion shows the flag; the country is located on the map.
+func mapQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	return game.Question{
+		Type:    "MAP",
+		Code:    country.Code,
+		FlagURL: country.FlagURL(),
+		Answer:  country.Name,
+	}, true
+}
+
+// typedQuestion shows the flag; the country name is typed in.
+func typedQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	question, _ := mapQuestion(country, options, rng)
+	question.Type = "TYPED"
+	return question, true
+}
+
+// reverseQuestion shows the country name with four flags to pick from. The
+// options are flag URLs and the answer is the country's ISO code.
+func reverseQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	flags := []string{country.FlagURL()}
+	for _, other := range pickDistractors(options, country, 3, rng) {
+		flags = append(flags, other.FlagURL())
+	}
+	shuffleOptions(flags, rng)
+
+	return game.Question{
+		Type:    "REVERSE",
+		Code:    country.Code,
+		Country: country.Name,
+		Options: flags,
+		Answer:  country.Code,
+	}, true
+}
+
+// capitalQuestion asks for the capital of the country.
+func capitalQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	if country.Capital == "" {
+		return game.Question{}, false
+	}
+
+	var capitals []string
+	for _, other := range options {
+		if other.Capital != "" && other.Capital != country.Capital {
+			capitals = append(capitals, other.Capital)
+		}
+	}
+	if len(capitals) < 3 {
+		return game.Question{}, false
+	}
+
+	question := optionsQuestion("CAPITAL", country, country.Capital, pickStrings(capitals, 3, rng), rng)
+	question.Prompt = fmt.Sprintf("What is the capital of %s?", country.Name)
+	return question, true
+}
+
+// continentQuestion asks which continent the flag's country is in.
+func continentQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	if !containsString(continents, country.Continent) {
+		return game.Question{}, false
+	}
+
+	var others []string
+	for _, continent := range continents {
+		if continent != country.Continent {
+			others = append(others, continent)
+		}
+	}
+
+	question := optionsQuestion("CONTINENT", country, country.Continent, pickStrings(others, 3, rng), rng)
+	question.Prompt = "Which continent is this country in?"
+	return question, true
+}
+
+// neighbourQuestion asks which of four countries borders the flag's country.
+// Only countries of options are offered, so the question needs one neighbour
+// and three countries that aren't neighbours among them.
+func neighbourQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	var neighbours, others []string
+	for _, other := range options {
+		switch {
+		case other.Code == country.Code:
+		case containsString(country.Neighbours, other.Code):
+			neighbours = append(neighbours, other.Name)
+		default:
+			others = append(others, other.Name)
+		}
+	}
+	if len(neighbours) == 0 || len(others) < 3 {
+		return game.Question{}, false
+	}
+
+	answer := neighbours[rng.Intn(len(neighbours))]
+	question := optionsQuestion("NEIGHBOUR", country, answer, pickStrings(others, 3, rng), rng)
+	question.Prompt = fmt.Sprintf("Which of these countries borders %s?", country.Name)
+	return question, true
+}
+
+// eurovisionQuestion asks for the year the flag's country first entered the
+// Eurovision Song Contest.
+func eurovisionQuestion(country catalog.Country, options []catalog.Country, rng *rand.Rand) (game.Question, bool) {
+	if country.EurovisionDebut == 0 {
+		return game.Question{}, false
+	}
+
+	var years []string
+	seen := map[int]bool{country.EurovisionDebut: true}
+	for _, other := range options {
+		if other.EurovisionDebut != 0 && !seen[other.EurovisionDebut] {
+			seen[other.EurovisionDebut] = true
+			years = append(years, strconv.Itoa(other.EurovisionDebut))
+		}
+	}
+	if len(years) < 3 {
+		return game.Question{}, false
+	}
+
+	answer := strconv.Itoa(country.EurovisionDebut)
+	question := optionsQuestion("EUROVISION", country, answer, pickStrings(years, 3, rng), rng)
+	question.Prompt = "In which year did this country first compete in Eurovision?"
+	return question, true
+}
+
+// pickStrings returns n random elements of values.
+func pickStrings(values []string, n int, rng *rand.Rand) []string {
+	values = append([]string(nil), values...)
+	shuffleOptions(values, rng)
+	if len(values) < n {
+		return values
+	}
+	return values[:n]
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package internals
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+	"github.com/adimail/fun-with-flags/internals/packs"
+)
+
+func TestOptionPool(t *testing.T) {
+	countries := &catalog.Catalog{Countries: make([]catalog.Country, 30)}
+	for _, size := range []int{0, 3, minOptionPool - 1, minOptionPool, 20} {
+		pool := make([]catalog.Country, size)
+		want := len(countries.Countries)
+		if size >= minOptionPool {
+			want = size
+		}
+		if got := len(optionPool(pool, countries)); got != want {
+			t.Errorf("pool of %d draws options from %d countries, want %d", size, got, want)
+		}
+	}
+}
+
+func TestGenerateQuestionsOptions(t *testing.T) {
+	chdirRoot(t)
+	store := packs.New()
+	packsOnce.Do(func() {})
+	saved := questionPacks
+	questionPacks = store
+	t.Cleanup(func() { questionPacks = saved })
+
+	countries, err := loadCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := []string{"AL", "AT", "CH", "DE", "DK", "ES", "FR", "IT"}
+	pack, _ := store.Add(packs.Pack{Name: "Eight", Codes: codes})
+	names := make(map[string]bool)
+	flags := make(map[string]bool)
+	for _, code := range codes {
+		country, _ := countries.ByCode(code)
+		names[country.Name] = true
+		flags[country.FlagURL()] = true
+	}
+
+	tests := []struct {
+		name           string
+		gameType       string
+		packID, region string
+		within         map[string]bool
+	}{
+		{"pack, names", "MCQ", pack.ID, "", names},
+		{"pack, flags", "REVERSE", pack.ID, "", flags},
+		{"region, names", "MCQ", "", strings.Join(codes, ","), names},
+		{"pack and region", "MCQ", pack.ID, "europe", names},
+	}
+	for _, tt := range tests {
+		questions, err := generateQuestions(8, tt.gameType, nil, "", tt.packID, tt.region)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, question := range questions {
+			for _, option := range question.Options {
+				if !tt.within[option] {
+					t.Errorf("%s: option %q of %s is not in the pack or region", tt.name, option, question.Code)
+				}
+			}
+		}
+	}
+}
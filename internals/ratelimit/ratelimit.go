@@ -0,0 +1,80 @@
+// Package ratelimit caps how often each client may do something, such as
+// logging in or reporting answers, within a fixed window of time.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// window counts the hits of one key since it started.
+type window struct {
+	start time.Time
+	hits  int
+}
+
+// Limiter allows each key up to Limit hits per Window. Keys are usually a
+// client address or an account name. It is safe for concurrent use.
+type Limiter struct {
+	Limit  int
+	Window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*window
+	pruned  time.Time // when ended windows were last forgotten
+}
+
+// New returns a Limiter allowing limit hits per key in every window.
+func New(limit int, per time.Duration) *Limiter {
+	return &Limiter{
+		Limit:   limit,
+		Window:  per,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow records a hit for key at now and reports whether it is within the
+// limit. Hits over the limit are not counted, so a client that keeps trying
+// is let through again once its window ends.
+func (l *Limiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.pruned) >= l.Window {
+		l.prune(now)
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.Window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	if w.hits >= l.Limit {
+		return false
+	}
+	w.hits++
+	return true
+}
+
+// RetryAfter returns how long key has to wait before its next hit is
+// allowed, 0 if it is allowed now.
+func (l *Limiter) RetryAfter(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || w.hits < l.Limit || now.Sub(w.start) >= l.Window {
+		return 0
+	}
+	return w.start.Add(l.Window).Sub(now)
+}
+
+// prune forgets the windows that ended, so keys seen once don't pile up.
+func (l *Limiter) prune(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.Window {
+			delete(l.windows, key)
+		}
+	}
+	l.pruned = now
+}
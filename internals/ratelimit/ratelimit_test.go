@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	l := New(3, time.Minute)
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("a", start.Add(time.Duration(i)*time.Second)) {
+			t.Fatalf("hit %d refused, want the first 3 allowed", i+1)
+		}
+	}
+	if l.Allow("a", start.Add(10*time.Second)) {
+		t.Error("hit over the limit allowed")
+	}
+	if !l.Allow("b", start.Add(10*time.Second)) {
+		t.Error("another key was refused")
+	}
+
+	// Refused hits don't count, so the key is let through when its window
+	// ends, however often it kept trying
+	for i := 0; i < 10; i++ {
+		l.Allow("a", start.Add(30*time.Second))
+	}
+	if !l.Allow("a", start.Add(time.Minute)) {
+		t.Error("hit refused after the window ended")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	l := New(2, time.Minute)
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	if wait := l.RetryAfter("a", start); wait != 0 {
+		t.Errorf("unknown key waits %v, want 0", wait)
+	}
+	l.Allow("a", start)
+	if wait := l.RetryAfter("a", start); wait != 0 {
+		t.Errorf("key under the limit waits %v, want 0", wait)
+	}
+	l.Allow("a", start.Add(20*time.Second))
+	if wait := l.RetryAfter("a", start.Add(45*time.Second)); wait != 15*time.Second {
+		t.Errorf("key at the limit waits %v, want 15s", wait)
+	}
+	if wait := l.RetryAfter("a", start.Add(time.Minute)); wait != 0 {
+		t.Errorf("key waits %v after its window, want 0", wait)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	l := New(1, time.Minute)
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	l.Allow("a", start)
+	l.Allow("b", start.Add(30*time.Second))
+	l.Allow("c", start.Add(80*time.Second))
+
+	if _, ok := l.windows["a"]; ok {
+		t.Error("ended window of a kept")
+	}
+	if _, ok := l.windows["b"]; !ok {
+		t.Error("open window of b forgotten")
+	}
+}
@@ -0,0 +1,213 @@
+package internals
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/adimail/fun-with-flags/internals/catalog"
+)
+
+// regions are the named play areas besides the continents of the catalog, by
+// ISO code. Members outside the catalog are never asked about but still
+// count toward the area shown on the map.
+var regions = map[string][]string{
+	"balkans":  {"AL", "BA", "BG", "GR", "HR", "ME", "MK", "RO", "RS", "SI", "XK"},
+	"caucasus": {"AM", "AZ", "GE"},
+	"nordics":  {"DK", "FI", "IS", "NO", "SE"},
+}
+
+// regionMembers returns the ISO codes of the countries of a region, ignoring
+// case: a named region (see regions), a continent of the catalog, e.g.
+// "Europe", or a custom comma separated list of codes, e.g. "AL,GR,MK".
+//
+// Parameters:
+//   - region: The region name or list of codes
+//   - countries: The catalog, for the continents
+//
+// Returns:
+//   - []string: The ISO codes, in upper case
+//   - error: An unknown region or country code
+func regionMembers(region string, countries *catalog.Catalog) ([]string, error) {
+	region = strings.TrimSpace(region)
+	if members, ok := regions[strings.ToLower(region)]; ok {
+		return members, nil
+	}
+
+	var members []string
+	for _, country := range countries.Countries {
+		if country.Continent != "" && strings.EqualFold(country.Continent, region) {
+			members = append(members, country.Code)
+		}
+	}
+	if len(members) > 0 {
+		return members, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range strings.Split(region, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if _, ok := catalog.ISO3(code); !ok {
+			return nil, fmt.Errorf("unknown region or country code %q", code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			members = append(members, code)
+		}
+	}
+	return members, nil
+}
+
+// regionCodes returns the codes of the countries of the catalog in a region,
+// the countries questions can be asked about there.
+func regionCodes(region string, countries *catalog.Catalog) ([]string, error) {
+	members, err := regionMembers(region, countries)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	for _, code := range members {
+		if _, ok := countries.ByCode(code); ok {
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// validateRegion checks the region requested for a game of numQuestions
+// questions: each question asks about another country, so the region needs
+// at least as many countries of the catalog. An empty region plays on the
+// whole world.
+func validateRegion(region string, numQuestions int) error {
+	if region == "" {
+		return nil
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		return err
+	}
+	codes, err := regionCodes(region, countries)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("no countries to ask about in region %q", region)
+	}
+	if len(codes) < numQuestions {
+		return fmt.Errorf("countries to ask about in region %q: %d, fewer than the %d questions", region, len(codes), numQuestions)
+	}
+	return nil
+}
+
+// regionBounds computes the area of a region to show on the map: the
+// bounding box of the outlines of its countries in countries.geo.json, and
+// of the catalog coordinates of countries without an outline.
+//
+// Parameters:
+//   - region: The region, see regionMembers
+//
+// Returns:
+//   - []float64: West, south, east and north in degrees, the order of a
+//     GeoJSON "bbox", or nil for an empty region
+//   - error: An unknown region, or a region without any known location
+func regionBounds(region string) ([]float64, error) {
+	if region == "" {
+		return nil, nil
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	members, err := regionMembers(region, countries)
+	if err != nil {
+		return nil, err
+	}
+	world, err := loadWorld()
+	if err != nil {
+		return nil, err
+	}
+
+	outlines := make(map[string][4]float64)
+	for i := range world.Features {
+		if code, ok := catalog.ISO2(world.Features[i].ID); ok {
+			outlines[code] = world.Features[i].Bounds()
+		}
+	}
+
+	var bounds []float64
+	extend := func(west, south, east, north float64) {
+		if bounds == nil {
+			bounds = []float64{west, south, east, north}
+			return
+		}
+		bounds[0] = min(bounds[0], west)
+		bounds[1] = min(bounds[1], south)
+		bounds[2] = max(bounds[2], east)
+		bounds[3] = max(bounds[3], north)
+	}
+	for _, code := range members {
+		if b, ok := outlines[code]; ok {
+			extend(b[0], b[1], b[2], b[3])
+		} else if country, ok := countries.ByCode(code); ok {
+			extend(country.Lon, country.Lat, country.Lon, country.Lat)
+		}
+	}
+	if bounds == nil {
+		return nil, fmt.Errorf("no known location in region %q", region)
+	}
+	return bounds, nil
+}
+
+// regionsHandler lists the named regions and continents games can be
+// restricted to, with the countries asked about and the area of the map to
+// show.
+//
+// HTTP Method: GET
+//
+// Response:
+//   - 200: [{"name", "countries", "bbox"}], sorted by name
+func regionsHandler(w http.ResponseWriter, r *http.Request) {
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	names := make(map[string]bool)
+	for name := range regions {
+		names[name] = true
+	}
+	for _, country := range countries.Countries {
+		if country.Continent != "" {
+			names[strings.ToLower(country.Continent)] = true
+		}
+	}
+
+	list := []map[string]interface{}{}
+	for name := range names {
+		codes, err := regionCodes(name, countries)
+		if err != nil || len(codes) == 0 {
+			continue
+		}
+		bounds, err := regionBounds(name)
+		if err != nil {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"name":      name,
+			"countries": codes,
+			"bbox":      bounds,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i]["name"].(string) < list[j]["name"].(string)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,297 @@
+package internals
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/achievements"
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultRoundTime  = 20 // seconds
+	roundSummaryPause = 3 * time.Second
+)
+
+// runRounds drives a synchronized room from the first question to the last.
+// For every question it opens a round, pushes the question to all players at
+// once and waits until everyone has answered or the round timer expires.
+// It then broadcasts a round summary and pauses briefly before advancing.
+//
+// Parameters:
+//   - room: Pointer to the Room instance, must have Synchronized set
+//
+// Events broadcast to the room:
+//   - "new_question": The question for the round, with question_index and round_time
+//   - "round_summary": Results of the round (see roundSummary)
+//   - "winner": Elimination rooms only, the last player standing
+//   - "all_players_finished": After the last round
+func runRounds(room *game.Room) {
+	roundTime := time.Duration(room.RoundTime) * time.Second
+
+	for i := 0; i < len(room.Questions); i++ {
+		mu.Lock()
+		open := rooms[room.Code] == room
+		lastStanding := room.GameMode == "ELIMINATION" && len(room.Players) <= 1
+		mu.Unlock()
+		if !open {
+			return
+		}
+		if lastStanding {
+			break
+		}
+
+		question, err := getQuestion(room, i)
+		if err != nil {
+			slog.Error("Failed to get question for round", "room", room.Code, "question", i, "error", err)
+			return
+		}
+		question["question_index"] = i
+		question["round_time"] = room.RoundTime
+
+		round := &game.Round{
+			Index:     i,
+			StartedAt: time.Now(),
+			Answers:   make(map[string]*game.RoundAnswer),
+			Done:      make(chan struct{}),
+		}
+
+		mu.Lock()
+		room.Round = round
+		playing := len(room.Players)
+		mu.Unlock()
+
+		broadcastToRoom(room, map[string]interface{}{
+			"event": "new_question",
+			"data":  question,
+		})
+		questionsServed.Add(float64(playing), question["type"].(string))
+
+		select {
+		case <-round.Done:
+		case <-time.After(roundTime):
+		}
+
+		mu.Lock()
+		room.Round = nil
+		summary := roundSummary(room, round)
+		mu.Unlock()
+
+		broadcastToRoom(room, map[string]interface{}{
+			"event": "round_summary",
+			"data":  summary,
+		})
+
+		if room.GameMode == "ELIMINATION" && applyEliminations(room, round) {
+			break
+		}
+
+		time.Sleep(roundSummaryPause)
+	}
+
+	if room.GameMode == "ELIMINATION" {
+		var event map[string]interface{}
+		mu.Lock()
+		if winner := eliminationWinner(room); winner != nil {
+			event = map[string]interface{}{
+				"event": "winner",
+				"data": map[string]interface{}{
+					"id":       winner.ID,
+					"username": winner.Username,
+					"score":    winner.Score,
+				},
+			}
+		}
+		mu.Unlock()
+		if event != nil {
+			broadcastToRoom(room, event)
+		}
+	}
+
+	mu.Lock()
+	for _, player := range room.Players {
+		player.Completed = true
+	}
+	mu.Unlock()
+
+	if !room.JuryFinale {
+		recordGameResults(room)
+	}
+	mu.Lock()
+	results := finalResults(room)
+	mu.Unlock()
+	broadcastToRoom(room, map[string]interface{}{
+		"event": "all_players_finished",
+		"data":  results,
+	})
+
+	if room.JuryFinale {
+		runJuryFinale(room)
+	}
+}
+
+// validateRoundsFit checks that every round of a synchronized room, with the
+// countdown and the pauses between rounds, ends before the time limit, which
+// would otherwise cut the game short with "time_over".
+func validateRoundsFit(req *game.CreateRoomRequest) error {
+	countdown := 4 * time.Second
+	round := time.Duration(req.RoundTime)*time.Second + roundSummaryPause
+	needed := countdown + time.Duration(req.NumQuestions)*round
+	if limit := time.Duration(req.TimeLimit) * time.Minute; needed > limit {
+		return fmt.Errorf("%d rounds of %d seconds take %d minutes, more than the time limit of %d minutes",
+			req.NumQuestions, req.RoundTime, int((needed+time.Minute-1)/time.Minute), req.TimeLimit)
+	}
+	return nil
+}
+
+// submitRoundAnswer records a player's answer for the open round of a
+// synchronized room. Only the first answer of each player counts, and answers
+// for any question other than the open one are rejected.
+//
+// Parameters:
+//   - room: Pointer to the Room instance
+//   - player: The player submitting the answer
+//   - conn: The player's WebSocket connection, used for the answer_result reply
+//   - questionIndex: The index of the question being answered
+//   - answer: The submitted answer
+//
+// Returns:
+//   - error: nil if the answer was recorded, error describing why it was refused otherwise
+func submitRoundAnswer(room *game.Room, player *game.Player, conn *websocket.Conn, questionIndex int, answer string) error {
+	mu.Lock()
+	round := room.Round
+	if player.Eliminated {
+		mu.Unlock()
+		return errors.New("you have been eliminated")
+	}
+	if round == nil || round.Index != questionIndex {
+		mu.Unlock()
+		return errors.New("this question is not open for answers")
+	}
+	if _, answered := round.Answers[player.ID]; answered {
+		mu.Unlock()
+		return errors.New("you have already answered this question")
+	}
+
+	question := room.Questions[strconv.Itoa(questionIndex)]
+	result := checkAnswer(question, answer, room.Tolerance)
+	isCorrect := result.Accepted
+	round.Answers[player.ID] = &game.RoundAnswer{
+		PlayerID: player.ID,
+		Username: player.Username,
+		Answer:   answer,
+		Correct:  isCorrect,
+		Elapsed:  time.Since(round.StartedAt),
+	}
+	recordAnswer(player, questionIndex, isCorrect, round.Answers[player.ID].Elapsed)
+	recordAnswerStats(question, isCorrect, round.Answers[player.ID].Elapsed)
+	var score map[string]interface{}
+	streak := 0
+	if isCorrect {
+		player.Score += answerPoints(room, player, questionIndex)
+		score = scoreEvent(room, player)
+		streak = answerStreak(player, questionIndex)
+	}
+	mu.Unlock()
+
+	message := map[string]interface{}{
+		"event": "answer_result",
+		"data":  answerResult(question, answer, result),
+	}
+	auditMessage(room, player.ID, message)
+	if err := writeJSON(conn, message); err != nil {
+		slog.Warn("Error sending validation response to player", "room", room.Code, "player", player.ID, "error", err)
+	}
+
+	if isCorrect {
+		broadcastToRoom(room, score)
+		awardBadges(room, player, achievements.Event{
+			Type:   achievements.AnswerEvent,
+			Streak: streak,
+		})
+	}
+
+	checkRoundComplete(room)
+	return nil
+}
+
+// checkRoundComplete closes the open round early once every player still in
+// the room has answered. It is also called when a player leaves mid-round.
+func checkRoundComplete(room *game.Room) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	round := room.Round
+	if round == nil {
+		return
+	}
+
+	for _, player := range room.Players {
+		if _, answered := round.Answers[player.ID]; !answered {
+			return
+		}
+	}
+
+	select {
+	case <-round.Done:
+	default:
+		close(round.Done)
+	}
+}
+
+// roundSummary builds the payload of the "round_summary" event: the correct
+// answer, each player's result ordered by answer time, and the fastest
+// correct answer if there was one. Players who did not answer in time are
+// listed with answered set to false.
+func roundSummary(room *game.Room, round *game.Round) map[string]interface{} {
+	question := room.Questions[strconv.Itoa(round.Index)]
+
+	answers := make([]*game.RoundAnswer, 0, len(round.Answers))
+	for _, answer := range round.Answers {
+		answers = append(answers, answer)
+	}
+	sort.Slice(answers, func(i, j int) bool {
+		return answers[i].Elapsed < answers[j].Elapsed
+	})
+
+	results := []map[string]interface{}{}
+	var fastest map[string]interface{}
+	for _, answer := range answers {
+		result := map[string]interface{}{
+			"id":       answer.PlayerID,
+			"username": answer.Username,
+			"answer":   answer.Answer,
+			"answered": true,
+			"correct":  answer.Correct,
+			"time_ms":  answer.Elapsed.Milliseconds(),
+		}
+		results = append(results, result)
+
+		if fastest == nil && answer.Correct {
+			fastest = result
+		}
+	}
+
+	for _, player := range room.Players {
+		if _, answered := round.Answers[player.ID]; !answered {
+			results = append(results, map[string]interface{}{
+				"id":       player.ID,
+				"username": player.Username,
+				"answered": false,
+				"correct":  false,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"question_index": round.Index,
+		"correct_answer": question.Answer,
+		"results":        results,
+		"fastest":        fastest,
+	}
+}
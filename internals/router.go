@@ -1,6 +1,7 @@
 package internals
 
 import (
+	"log/slog"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -8,7 +9,16 @@ import (
 
 func Router() *mux.Router {
 	r := mux.NewRouter()
-	// r.Use(loggingMiddleware)
+	r.Use(loggingMiddleware)
+	r.Use(metricsMiddleware)
+
+	// Map answers are checked against the country outlines, see locateAnswer
+	if _, err := loadMapWorld(); err != nil {
+		slog.Error("Failed to load country outlines", "error", err)
+	}
+	reportCountryData()
+	// Hashed now, as the data files were just read, see versionHandler
+	loadDatasetVersions()
 
 	// Serve static files under "/static" URL path
 	fs := http.FileServer(http.Dir("./frontend/static"))
@@ -16,6 +26,14 @@ func Router() *mux.Router {
 
 	// WebSocket endpoint for "/ws"
 	r.HandleFunc("/ws", HandleWebSocket)
+	r.HandleFunc("/ws/replay/{id}", replayHandler)
+
+	// Prometheus metrics, see metricsHandler
+	r.HandleFunc("/metrics", metricsHandler).Methods("GET")
+
+	// Probes for load balancers and orchestrators, see health.go
+	r.HandleFunc("/healthz", healthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", readyzHandler).Methods("GET")
 
 	//
 	// Route handlers that serves HTML pages
@@ -51,6 +69,11 @@ func Router() *mux.Router {
 		http.ServeFile(w, r, "./frontend/game.singleplayer.html")
 	})
 
+	// "/login"
+	r.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./frontend/login.html")
+	})
+
 	// "/map" Single player game
 	r.HandleFunc("/map", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "./frontend/worldmap.html")
@@ -58,11 +81,44 @@ func Router() *mux.Router {
 
 	// game state
 	r.HandleFunc("/api/singleplayer", SinglePlayerHandler).Methods("GET")
+	r.HandleFunc("/api/singleplayer/answer", checkAnswerHandler).Methods("POST")
+	r.HandleFunc("/api/flag/{code}", flagHandler).Methods("GET")
+	r.HandleFunc("/api/stats", statsHandler).Methods("GET")
+	r.HandleFunc("/api/stats/answer", recordStatsHandler).Methods("POST")
+	r.HandleFunc("/api/practice/next", practiceNextHandler).Methods("GET")
+	r.HandleFunc("/api/auth/register", registerHandler).Methods("POST")
+	r.HandleFunc("/api/auth/login", loginHandler).Methods("POST")
+	r.HandleFunc("/api/auth/logout", logoutHandler).Methods("POST")
+	r.HandleFunc("/api/auth/me", meHandler).Methods("GET")
+	r.HandleFunc("/api/profile", myProfileHandler).Methods("GET")
+	r.HandleFunc("/api/profile", updateProfileHandler).Methods("PUT")
+	r.HandleFunc("/api/profile/claim", claimHandler).Methods("POST")
+	r.HandleFunc("/api/profile/history", historyHandler).Methods("GET")
+	r.HandleFunc("/api/leaderboard", leaderboardHandler).Methods("GET")
+	r.HandleFunc("/api/profile/{username}", profileHandler).Methods("GET")
+	r.HandleFunc("/api/badges", badgesHandler).Methods("GET")
+	r.HandleFunc("/api/packs", listPacksHandler).Methods("GET")
+	r.HandleFunc("/api/packs", createPackHandler).Methods("POST")
+	r.HandleFunc("/api/packs/{id}", packHandler).Methods("GET")
+	r.HandleFunc("/api/geo", geoHandler).Methods("GET")
+	r.HandleFunc("/api/regions", regionsHandler).Methods("GET")
+	r.HandleFunc("/api/version", versionHandler).Methods("GET")
+	r.HandleFunc("/api/practice/answer", practiceAnswerHandler).Methods("POST")
 	r.HandleFunc("/api/createroom", createRoomHandler).Methods("POST")
 	r.HandleFunc("/api/joinroom", joinRoomHandler).Methods("POST")
 	r.HandleFunc("/api/room/{id}", getRoomHandler).Methods("GET")
 	r.HandleFunc("/api/rooms", adminHandler).Methods("GET")
 
+	// admin API, see requireAdmin
+	r.HandleFunc("/api/admin/rooms/{id}", adminRoomHandler).Methods("GET")
+	r.HandleFunc("/api/admin/rooms/{id}", adminDeleteRoomHandler).Methods("DELETE")
+	r.HandleFunc("/api/admin/rooms/{id}/end", adminEndRoomHandler).Methods("POST")
+	r.HandleFunc("/api/admin/announce", announceHandler).Methods("POST")
+	r.HandleFunc("/api/admin/connections", connectionsHandler).Methods("GET")
+	r.HandleFunc("/api/admin/accounts/{username}", adminAccountHandler).Methods("PUT")
+	r.HandleFunc("/api/admin/games", gamesHandler).Methods("GET")
+	r.HandleFunc("/api/admin/games/{id}", gameLogHandler).Methods("GET")
+
 	//
 	// Error handlers
 	//
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 func SinglePlayerHandler(w http.ResponseWriter, r *http.Request) {
@@ -15,13 +16,134 @@ func SinglePlayerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	gameType := r.Header.Get("game-type")
+	switch gameType {
+	case "MCQ", "MAP", "REVERSE", "TYPED":
+	default:
+		http.Error(w, "Invalid game type", http.StatusBadRequest)
+		return
+	}
+
+	// Optional comma separated list of question types to mix, e.g. "MCQ,CAPITAL"
+	var questionTypes []string
+	if header := r.Header.Get("X-Question-Types"); header != "" && gameType == "MCQ" {
+		questionTypes = strings.Split(header, ",")
+		if err := validateQuestionTypes(questionTypes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Optional harder flags, e.g. "grayscale"
+	flagVariant := r.Header.Get("X-Flag-Variant")
+	if err := validateFlagVariant(flagVariant); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Optional difficulty: "easy", "medium", "hard" or "adaptive"
+	difficulty := r.Header.Get("X-Difficulty")
+	if err := validateDifficulty(difficulty); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flagVariant = resolveFlagVariant(flagVariant, difficulty)
 
-	questions, err := generateQuestions(numQuestions, gameType)
+	// Optional play area, e.g. "nordics", see regionMembers
+	region := r.Header.Get("X-Region")
+	if err := validateRegion(region, numQuestions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Optional custom question pack, see createPackHandler
+	packID := r.Header.Get("X-Question-Pack")
+	if err := validatePack(packID, region, numQuestions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	questions, err := generateQuestions(numQuestions, gameType, questionTypes, difficulty, packID, region)
 	if err != nil {
 		http.Error(w, "Failed to generate questions: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	applyFlagVariant(questions, flagVariant)
+	for _, question := range questions {
+		questionsServed.Inc(question.Type)
+	}
+
+	// The area to show on the map comes back as "west,south,east,north"
+	bounds, err := regionBounds(region)
+	if err != nil {
+		http.Error(w, "Failed to locate the region: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if bounds != nil {
+		values := make([]string, len(bounds))
+		for i, value := range bounds {
+			values[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		w.Header().Set("X-Region-Bbox", strings.Join(values, ","))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(questions)
 }
+
+// singlePlayerTolerance is the number of edits a typed answer of a single
+// player game may be off by, see catalog.Catalog.Match. The server sets it
+// so that players can't loosen the matching for themselves.
+const singlePlayerTolerance = 2
+
+// checkAnswerHandler validates a typed answer for single player games,
+// where the matching against names and aliases has to happen on the server.
+// Near misses are accepted within singlePlayerTolerance edits.
+//
+// HTTP Method: POST
+// Content-Type: application/json
+//
+// Request Body:
+//   - code: ISO code of the country asked about (the question's "code")
+//   - answer: The name typed by the player
+//
+// Response:
+//   - 200: {"correct", "near_miss", "matched", "correct_answer"}
+//   - 400: Invalid request parameters
+//   - 404: Unknown country code
+func checkAnswerHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code   string `json:"code"`
+		Answer string `json:"answer"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid JSON format"})
+		return
+	}
+
+	countries, err := loadCatalog()
+	if err != nil {
+		http.Error(w, "Failed to load countries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	country, ok := countries.ByCode(req.Code)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "Country not found"})
+		return
+	}
+
+	result := countries.Match(country.Code, req.Answer, singlePlayerTolerance)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"correct":        result.Accepted,
+		"near_miss":      result.NearMiss,
+		"matched":        result.Matched,
+		"correct_answer": country.Name,
+	})
+}
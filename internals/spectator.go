@@ -0,0 +1,78 @@
+package internals
+
+import (
+	"log/slog"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/websocket"
+)
+
+// handleSpectator registers a WebSocket connection as a spectator of a room.
+// Spectators receive every event broadcast to the room (countdown, scores,
+// finishes) but are never counted as players: they are kept out of
+// room.Players, so they don't take a seat under the 9-player cap, don't
+// block allPlayersCompleted and can join after the game has started.
+//
+// Parameters:
+//   - conn: The spectator's WebSocket connection
+//   - room: Pointer to the Room instance being watched
+//   - username: Display name of the spectator, "Spectator" when empty
+//   - logger: The logger of the connection, with its request ID and room
+//
+// On join the spectator receives a "spectating" event with a snapshot of the
+// room so a late joiner can render the current leaderboard. Any message other
+// than "leave" is ignored; the spectator is removed when it leaves or its
+// socket closes.
+func handleSpectator(conn *websocket.Conn, room *game.Room, username string, logger *slog.Logger) {
+	if username == "" {
+		username = "Spectator"
+	}
+
+	spectator := &game.Player{
+		ID:       generatePlayerID(),
+		Username: username,
+		Conn:     conn,
+	}
+
+	mu.Lock()
+	room.Spectators[conn] = spectator
+	mu.Unlock()
+	logger = logger.With("spectator", spectator.ID)
+	logger.Info("Spectator joined the room", "username", spectator.Username)
+
+	err := writeJSON(conn, map[string]interface{}{
+		"event": "spectating",
+		"data": map[string]interface{}{
+			"code":         room.Code,
+			"host":         room.Hostname,
+			"players":      getSerializablePlayers(room),
+			"start":        room.Start,
+			"timeLimit":    room.TimeLimit,
+			"numQuestions": len(room.Questions),
+			"gamemode":     room.GameMode,
+		},
+	})
+	if err != nil {
+		logger.Warn("Error sending room snapshot to spectator", "error", err)
+	}
+
+	for {
+		var message struct {
+			Event string `json:"event"`
+		}
+
+		if err := conn.ReadJSON(&message); err != nil {
+			logger.Info("WebSocket connection closed for spectator", "error", err)
+			break
+		}
+
+		if message.Event == "leave" {
+			logger.Info("Spectator left the room")
+			break
+		}
+	}
+
+	mu.Lock()
+	delete(room.Spectators, conn)
+	mu.Unlock()
+}
@@ -0,0 +1,174 @@
+// Package stats records how players answer the question about each country
+// and derives an empirical difficulty per flag from it.
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/jsonfile"
+)
+
+// maxTimes is the number of recent answer times kept per country for the
+// median.
+const maxTimes = 200
+
+// slowAnswer is the answer time at which the time component of the
+// difficulty reaches its maximum.
+const slowAnswer = 15 * time.Second
+
+// CountryStats are the answers recorded for one country code.
+type CountryStats struct {
+	Answers int     `json:"answers"`
+	Correct int     `json:"correct"`
+	TimesMs []int64 `json:"times_ms"` // most recent answer times, oldest first
+}
+
+// PercentCorrect returns the share of correct answers, from 0 to 100.
+func (c *CountryStats) PercentCorrect() float64 {
+	if c.Answers == 0 {
+		return 0
+	}
+	return 100 * float64(c.Correct) / float64(c.Answers)
+}
+
+// MedianTime returns the median of the recorded answer times.
+func (c *CountryStats) MedianTime() time.Duration {
+	if len(c.TimesMs) == 0 {
+		return 0
+	}
+	times := append([]int64(nil), c.TimesMs...)
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	middle := len(times) / 2
+	median := times[middle]
+	if len(times)%2 == 0 {
+		median = (times[middle-1] + times[middle]) / 2
+	}
+	return time.Duration(median) * time.Millisecond
+}
+
+// Difficulty scores how hard the flag is, from 0 (everyone answers it right
+// away) to 1 (nobody gets it). It mixes the share of wrong answers with the
+// median answer time. Both start from a neutral prior, so flags without
+// answers score 0.5 and a handful of answers only moves the score a little.
+func (c *CountryStats) Difficulty() float64 {
+	// Success rate with one correct and one wrong answer assumed
+	success := float64(c.Correct+1) / float64(c.Answers+2)
+
+	slowness := 0.5
+	if len(c.TimesMs) > 0 {
+		slowness = float64(c.MedianTime()) / float64(slowAnswer)
+		if slowness > 1 {
+			slowness = 1
+		}
+		// Weigh the median by how many times it is based on
+		weight := float64(len(c.TimesMs)) / float64(len(c.TimesMs)+2)
+		slowness = weight*slowness + (1-weight)*0.5
+	}
+
+	return 0.7*(1-success) + 0.3*slowness
+}
+
+// Stats holds the answer statistics of every country, keyed by ISO code.
+// It is safe for concurrent use.
+type Stats struct {
+	mu        sync.Mutex
+	countries map[string]*CountryStats
+	dirty     bool
+}
+
+// New returns empty statistics.
+func New() *Stats {
+	return &Stats{countries: make(map[string]*CountryStats)}
+}
+
+// Load reads statistics saved by Save. A missing file gives empty
+// statistics.
+func Load(file string) (*Stats, error) {
+	s := New()
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &s.countries); err != nil {
+		return nil, err
+	}
+	if s.countries == nil {
+		s.countries = make(map[string]*CountryStats)
+	}
+	return s, nil
+}
+
+// Save writes the statistics to file if they changed since the last save.
+// The file is replaced atomically.
+func (s *Stats) Save(file string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	if err := jsonfile.Write(file, s.countries, 0o644); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Record adds an answer about the country with the given code.
+func (s *Stats) Record(code string, correct bool, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.countries[code]
+	if !ok {
+		c = &CountryStats{}
+		s.countries[code] = c
+	}
+
+	c.Answers++
+	if correct {
+		c.Correct++
+	}
+	if elapsed > 0 {
+		c.TimesMs = append(c.TimesMs, elapsed.Milliseconds())
+		if len(c.TimesMs) > maxTimes {
+			c.TimesMs = c.TimesMs[len(c.TimesMs)-maxTimes:]
+		}
+	}
+	s.dirty = true
+}
+
+// Country returns a copy of the statistics of a country. Countries nobody
+// answered about yet return zero statistics.
+func (s *Stats) Country(code string) CountryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.countries[code]
+	if !ok {
+		return CountryStats{}
+	}
+	return CountryStats{
+		Answers: c.Answers,
+		Correct: c.Correct,
+		TimesMs: append([]int64(nil), c.TimesMs...),
+	}
+}
+
+// Difficulty returns the difficulty of a country's flag, see
+// CountryStats.Difficulty.
+func (s *Stats) Difficulty(code string) float64 {
+	c := s.Country(code)
+	return c.Difficulty()
+}
@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDifficulty(t *testing.T) {
+	record := func(answers int, correct bool, elapsed time.Duration) *CountryStats {
+		s := New()
+		for i := 0; i < answers; i++ {
+			s.Record("FR", correct, elapsed)
+		}
+		c := s.Country("FR")
+		return &c
+	}
+
+	if got := (&CountryStats{}).Difficulty(); got != 0.5 {
+		t.Errorf("difficulty without answers = %v, want 0.5", got)
+	}
+
+	easy := record(50, true, time.Second).Difficulty()
+	hard := record(50, false, 20*time.Second).Difficulty()
+	fewWrong := record(2, false, 20*time.Second).Difficulty()
+	if easy >= 0.2 {
+		t.Errorf("difficulty of a flag always answered right away = %v, want below 0.2", easy)
+	}
+	if hard <= 0.8 || hard > 1 {
+		t.Errorf("difficulty of a flag always missed = %v, want between 0.8 and 1", hard)
+	}
+	if fewWrong <= 0.5 || fewWrong >= hard {
+		t.Errorf("difficulty after 2 wrong answers = %v, want between 0.5 and %v", fewWrong, hard)
+	}
+
+	// Slower answers make a flag harder, all else equal
+	fast := record(10, true, 2*time.Second).Difficulty()
+	slow := record(10, true, 12*time.Second).Difficulty()
+	if fast >= slow {
+		t.Errorf("difficulty of fast answers %v, of slow answers %v, want fast < slow", fast, slow)
+	}
+}
+
+func TestMedianTime(t *testing.T) {
+	tests := []struct {
+		times []int64
+		want  time.Duration
+	}{
+		{nil, 0},
+		{[]int64{500}, 500 * time.Millisecond},
+		{[]int64{3000, 1000, 2000}, 2 * time.Second},
+		{[]int64{4000, 1000, 3000, 2000}, 2500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		c := CountryStats{TimesMs: tt.times}
+		if got := c.MedianTime(); got != tt.want {
+			t.Errorf("MedianTime(%v) = %v, want %v", tt.times, got, tt.want)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	s := New()
+	for i := 1; i <= maxTimes+10; i++ {
+		s.Record("DE", i%2 == 0, time.Duration(i)*time.Millisecond)
+	}
+	// Answers without a time count, but have no time to keep
+	s.Record("DE", true, 0)
+
+	c := s.Country("DE")
+	if c.Answers != maxTimes+11 || c.Correct != (maxTimes+10)/2+1 {
+		t.Errorf("DE has %d answers, %d correct", c.Answers, c.Correct)
+	}
+	if len(c.TimesMs) != maxTimes || c.TimesMs[0] != 11 {
+		t.Errorf("kept %d times from %v, want the %d most recent", len(c.TimesMs), c.TimesMs[0], maxTimes)
+	}
+
+	c.TimesMs[0] = 0
+	if s.Country("DE").TimesMs[0] != 11 {
+		t.Error("changing the statistics returned by Country changed the store")
+	}
+	if got := s.Country("XX"); got.Answers != 0 {
+		t.Errorf("statistics of an unknown country = %+v", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stats.json")
+
+	s := New()
+	s.Record("FR", true, time.Second)
+	s.Record("FR", false, 3*time.Second)
+	if err := s.Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.Difficulty("FR"), s.Difficulty("FR"); got != want {
+		t.Errorf("difficulty after loading = %v, want %v", got, want)
+	}
+
+	empty, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || empty.Difficulty("FR") != 0.5 {
+		t.Errorf("Load of a missing file = %v, %v", empty, err)
+	}
+}
@@ -0,0 +1,160 @@
+package internals
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+)
+
+// validateTeams checks the team names requested for a room. A room without
+// teams is valid; otherwise it needs 2 to 4 distinct, non-empty names of at
+// most 20 characters. Names are trimmed in place.
+func validateTeams(teams []string) error {
+	if len(teams) == 0 {
+		return nil
+	}
+	if len(teams) < 2 || len(teams) > 4 {
+		return errors.New("a room with teams needs between 2 and 4 teams")
+	}
+
+	seen := make(map[string]bool)
+	for i, team := range teams {
+		team = strings.TrimSpace(team)
+		if team == "" || len(team) > 20 {
+			return errors.New("team names must be between 1 and 20 characters")
+		}
+		if seen[strings.ToLower(team)] {
+			return errors.New("team names must be unique")
+		}
+		seen[strings.ToLower(team)] = true
+		teams[i] = team
+	}
+	return nil
+}
+
+// assignTeam returns the team a player joins. The requested team is honoured
+// when it exists in the room (case-insensitive), otherwise the player is
+// placed in the team with the fewest members. Rooms without teams return "".
+func assignTeam(room *game.Room, requested string) string {
+	if len(room.Teams) == 0 {
+		return ""
+	}
+
+	if team, ok := findTeam(room, requested); ok {
+		return team
+	}
+
+	members := make(map[string]int)
+	for _, player := range room.Players {
+		members[player.Team]++
+	}
+
+	smallest := room.Teams[0]
+	for _, team := range room.Teams[1:] {
+		if members[team] < members[smallest] {
+			smallest = team
+		}
+	}
+	return smallest
+}
+
+// findTeam looks up a team of the room by name, ignoring case, and returns
+// its name as defined when the room was created.
+func findTeam(room *game.Room, name string) (string, bool) {
+	for _, team := range room.Teams {
+		if strings.EqualFold(team, name) {
+			return team, true
+		}
+	}
+	return "", false
+}
+
+// teamScores sums the scores of the players in each team of the room.
+func teamScores(room *game.Room) map[string]int {
+	scores := make(map[string]int)
+	for _, team := range room.Teams {
+		scores[team] = 0
+	}
+	for _, player := range roomParticipants(room) {
+		if player.Team != "" {
+			scores[player.Team] += player.Score
+		}
+	}
+	return scores
+}
+
+// teamStandings ranks the teams of a room by their aggregated score. Ties
+// keep the order the teams were defined in. Rooms without teams return nil.
+func teamStandings(room *game.Room) []map[string]interface{} {
+	if len(room.Teams) == 0 {
+		return nil
+	}
+
+	scores := teamScores(room)
+	members := make(map[string][]string)
+	for _, player := range roomParticipants(room) {
+		members[player.Team] = append(members[player.Team], player.Username)
+	}
+
+	teams := append([]string(nil), room.Teams...)
+	sort.SliceStable(teams, func(i, j int) bool {
+		return scores[teams[i]] > scores[teams[j]]
+	})
+
+	standings := []map[string]interface{}{}
+	for i, team := range teams {
+		players := members[team]
+		if players == nil {
+			players = []string{}
+		}
+		standings = append(standings, map[string]interface{}{
+			"rank":    i + 1,
+			"team":    team,
+			"score":   scores[team],
+			"players": players,
+		})
+	}
+	return standings
+}
+
+// scoreEvent builds the "score" event broadcast when a player's score
+// changes. In team rooms it also carries the player's team, that team's
+// total and the totals of every team.
+func scoreEvent(room *game.Room, player *game.Player) map[string]interface{} {
+	data := map[string]interface{}{
+		"username": player.Username,
+		"score":    player.Score,
+	}
+
+	if len(room.Teams) > 0 {
+		scores := teamScores(room)
+		data["team"] = player.Team
+		data["team_score"] = scores[player.Team]
+		data["teams"] = scores
+	}
+
+	return map[string]interface{}{
+		"event": "score",
+		"data":  data,
+	}
+}
+
+// finalResults is the payload attached to the events that end a game
+// ("all_players_finished" and "time_over"): players ranked by score and, in
+// team rooms, the team standings. The caller must hold mu.
+func finalResults(room *game.Room) map[string]interface{} {
+	players := getSerializablePlayers(room)
+	sort.SliceStable(players, func(i, j int) bool {
+		return players[i]["score"].(int) > players[j]["score"].(int)
+	})
+
+	results := map[string]interface{}{
+		"players": players,
+	}
+	if standings := teamStandings(room); standings != nil {
+		results["teams"] = standings
+	}
+	return results
+}
@@ -1,38 +1,102 @@
 package internals
 
 import (
-	"encoding/csv"
-	"log"
+	"errors"
+	"log/slog"
 	"math/rand"
-	"os"
-	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/adimail/fun-with-flags/internals/catalog"
 	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/adimail/fun-with-flags/internals/packs"
+	"github.com/gorilla/websocket"
 )
 
 var mu sync.Mutex
 
+var (
+	countryCatalog *catalog.Catalog
+	catalogErr     error
+	catalogOnce    sync.Once
+)
+
+const (
+	countriesFile = "./data/countries.csv"
+	aliasesFile   = "./data/aliases.csv"
+)
+
+// loadCatalog reads data/countries.csv and data/aliases.csv on first use
+// and returns the cached catalog afterwards.
+func loadCatalog() (*catalog.Catalog, error) {
+	catalogOnce.Do(func() {
+		countryCatalog, catalogErr = catalog.Load(countriesFile)
+		if catalogErr == nil {
+			catalogErr = countryCatalog.LoadAliases(aliasesFile)
+		}
+	})
+	return countryCatalog, catalogErr
+}
+
+// StartRoomCleanup removes the empty rooms and prunes the audit logs (see
+// pruneAudit) at every interval.
 func StartRoomCleanup(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	pruneAudit()
 	for range ticker.C {
 		cleanupEmptyRooms()
+		pruneAudit()
+	}
+}
+
+// StartDataSaver writes the answer statistics, the practice progress, the
+// accounts and the profiles to disk at every interval.
+func StartDataSaver(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		saveData()
+	}
+}
+
+func saveData() {
+	if err := loadStats().Save(statsFile); err != nil {
+		slog.Error("Failed to save answer statistics", "error", err)
+	}
+	if err := loadPractice().Save(practiceFile); err != nil {
+		slog.Error("Failed to save practice progress", "error", err)
+	}
+	if err := loadAccounts().Save(accountsFile); err != nil {
+		slog.Error("Failed to save accounts", "error", err)
+	}
+	if err := loadProfiles().Save(profilesFile); err != nil {
+		slog.Error("Failed to save profiles", "error", err)
+	}
+	if err := loadPacks().Save(packsFile); err != nil {
+		slog.Error("Failed to save question packs", "error", err)
 	}
 }
 
 func cleanupEmptyRooms() {
 	mu.Lock()
-	defer mu.Unlock()
-
+	var empty []*game.Room
 	for roomID, room := range rooms {
 		if len(room.Players) == 0 {
-			log.Printf("Deleting empty room: %s", roomID)
+			slog.Info("Deleting empty room", "room", roomID)
+			empty = append(empty, room)
 			delete(rooms, roomID)
+			roomsCleanedUp.Inc()
 		}
 	}
+	mu.Unlock()
+
+	for _, room := range empty {
+		closeRoomConnections(room)
+	}
 }
 
 func newRandomGenerator() *rand.Rand {
@@ -45,72 +109,208 @@ func shuffleOptions(options []string, rng *rand.Rand) {
 	})
 }
 
-func shuffleCountries(rows [][]string, rng *rand.Rand) {
-	rng.Shuffle(len(rows), func(i, j int) {
-		rows[i], rows[j] = rows[j], rows[i]
+func shuffleCountries(countries []catalog.Country, rng *rand.Rand) {
+	rng.Shuffle(len(countries), func(i, j int) {
+		countries[i], countries[j] = countries[j], countries[i]
 	})
 }
 
-func selectRandomCountries(rows [][]string, count int, rng *rand.Rand) [][]string {
-	rng.Shuffle(len(rows), func(i, j int) {
-		rows[i], rows[j] = rows[j], rows[i]
-	})
-	if len(rows) < count {
-		return rows
+func selectRandomCountries(countries []catalog.Country, count int, rng *rand.Rand) []catalog.Country {
+	countries = append([]catalog.Country(nil), countries...)
+	shuffleCountries(countries, rng)
+	if len(countries) < count {
+		return countries
 	}
-	return rows[:count]
+	return countries[:count]
 }
 
-func generateQuestions(numQuestions int, gameType string) ([]game.Question, error) {
-	file, err := os.Open("./data/countries.csv")
+// generateQuestions draws numQuestions random countries from the catalog, or
+// from the question pack packID when given, weighted by difficulty (see
+// selectCountriesByDifficulty), and builds a question for each of them (see
+// questionGenerators). Ordered packs ask their countries in order instead, so
+// difficulty doesn't apply to them. A region (see regionMembers) keeps only
+// the countries in it, e.g. for map challenges played on part of the world.
+// Packs and regions must have been checked to hold at least numQuestions
+// countries (see validatePack). The wrong options come from the pack and
+// region too when they are large enough (see optionPool). Each question uses
+// one of questionTypes picked at random; when none are given, the question
+// type of the game type is used:
+//   - "MAP": the flag is shown and the country is located on the map
+//   - "TYPED": the flag is shown and the country name is typed in
+//   - "REVERSE": the country name is shown and the answer is picked among
+//     four flags; the answer is the country's ISO code
+//   - anything else: the flag is shown with four country names to pick from
+func generateQuestions(numQuestions int, gameType string, questionTypes []string, difficulty string, packID string, region string) ([]game.Question, error) {
+	countries, err := loadCatalog()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
+	pool := countries.Countries
+	var pack packs.Pack
+	if packID != "" {
+		var ok bool
+		if pack, ok = loadPacks().Get(packID); !ok {
+			return nil, errors.New("question pack not found")
+		}
+		pool = packCountries(pack, countries)
+	}
+	if region != "" {
+		codes, err := regionCodes(region, countries)
+		if err != nil {
+			return nil, err
+		}
+		pool = inRegion(pool, codes)
+		if len(pool) == 0 {
+			return nil, errors.New("no countries to ask about in the region")
+		}
+	}
+
+	if len(questionTypes) == 0 {
+		questionTypes = []string{questionTypeFor(gameType)}
 	}
 
 	rng := newRandomGenerator()
-	selectedCountries := selectRandomCountries(rows, numQuestions, rng)
+	var selectedCountries []catalog.Country
+	if pack.Ordered {
+		selectedCountries = pool[:min(numQuestions, len(pool))]
+	} else {
+		selectedCountries = selectCountriesByDifficulty(pool, numQuestions, difficulty, rng)
+	}
 
+	options := optionPool(pool, countries)
 	var questions []game.Question
-	for i, row := range selectedCountries {
-		countryName := row[0]
-		countryCode := row[1]
-		flagURL := filepath.Join("/static/svg", countryCode+".svg")
-
-		question := game.Question{
-			FlagURL: flagURL,
-			Answer:  countryName,
+	for _, country := range selectedCountries {
+		questions = append(questions, generateQuestion(country, options, questionTypes, rng))
+	}
+	return questions, nil
+}
+
+// inRegion returns the countries of pool whose codes are listed, keeping the
+// order of pool.
+func inRegion(pool []catalog.Country, codes []string) []catalog.Country {
+	listed := make(map[string]bool)
+	for _, code := range codes {
+		listed[code] = true
+	}
+
+	var selected []catalog.Country
+	for _, country := range pool {
+		if listed[country.Code] {
+			selected = append(selected, country)
 		}
+	}
+	return selected
+}
 
-		if gameType != "MAP" {
-			options := []string{countryName}
-			for j := 0; j < 3; j++ {
-				options = append(options, selectedCountries[(i+j+1)%len(selectedCountries)][0])
-			}
-			shuffleOptions(options, rng)
-			question.Options = options
+// pickDistractors returns n random countries other than answer, used as the
+// wrong options of a multiple choice question.
+func pickDistractors(countries []catalog.Country, answer catalog.Country, n int, rng *rand.Rand) []catalog.Country {
+	var others []catalog.Country
+	for _, country := range countries {
+		if country.Code != answer.Code {
+			others = append(others, country)
 		}
+	}
+	return selectRandomCountries(others, n, rng)
+}
 
-		questions = append(questions, question)
+// checkAnswer reports whether answer is the right answer to question.
+// Reverse questions are checked by ISO code, accepting either the code or the
+// flag URL of the chosen option. Typed questions are matched with
+// catalog.Match, allowing up to tolerance edits. Other questions compare the
+// country name exactly.
+func checkAnswer(question *game.Question, answer string, tolerance int) catalog.MatchResult {
+	switch question.Type {
+	case "REVERSE":
+		return catalog.MatchResult{
+			Accepted: strings.EqualFold(question.Answer, catalog.CodeFromFlagURL(answer)),
+		}
+	case "TYPED":
+		countries, err := loadCatalog()
+		if err != nil {
+			slog.Error("Failed to load catalog", "error", err)
+			return catalog.MatchResult{}
+		}
+		return countries.Match(question.Code, answer, tolerance)
 	}
-	return questions, nil
+	return catalog.MatchResult{Accepted: question.Answer == answer}
+}
+
+// answerResult builds the data of the "answer_result" event sent back to the
+// player who answered. Typed questions also say whether a near miss was
+// accepted and which name it was matched to.
+func answerResult(question *game.Question, answer string, result catalog.MatchResult) map[string]interface{} {
+	data := map[string]interface{}{
+		"correct_answer": question.Answer,
+		"chosen_answer":  answer,
+	}
+
+	if question.Type == "TYPED" {
+		data["correct"] = result.Accepted
+		data["near_miss"] = result.NearMiss
+		if result.Accepted {
+			data["matched"] = result.Matched
+		}
+	}
+	return data
+}
+
+// recordAnswer keeps a player's first answer to a question, for answer
+// streaks, the jury finale and the player's profile. Later answers to the
+// same question are ignored. The caller must hold mu.
+func recordAnswer(player *game.Player, questionIndex int, correct bool, elapsed time.Duration) {
+	if hasAnswered(player, questionIndex) {
+		return
+	}
+
+	player.Answers = append(player.Answers, game.AnswerRecord{
+		QuestionIndex: questionIndex,
+		Correct:       correct,
+		Elapsed:       elapsed,
+	})
+}
+
+// hasAnswered reports whether a player's answer to a question was recorded.
+func hasAnswered(player *game.Player, questionIndex int) bool {
+	for _, answer := range player.Answers {
+		if answer.QuestionIndex == questionIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// roomParticipants returns everyone playing in the room, including players
+// knocked out of an elimination room who now watch as spectators.
+func roomParticipants(room *game.Room) []*game.Player {
+	var participants []*game.Player
+	for _, player := range room.Players {
+		participants = append(participants, player)
+	}
+	for _, spectator := range room.Spectators {
+		if spectator.Eliminated {
+			participants = append(participants, spectator)
+		}
+	}
+	return participants
 }
 
 func getSerializablePlayers(room *game.Room) []map[string]interface{} {
 	players := []map[string]interface{}{}
-	for _, playerConn := range room.Players {
+	for _, playerConn := range roomParticipants(room) {
 		if playerConn != nil {
-			players = append(players, map[string]interface{}{
+			player := map[string]interface{}{
 				"id":       playerConn.ID,
 				"username": playerConn.Username,
 				"score":    playerConn.Score,
-			})
+				"team":     playerConn.Team,
+			}
+			if room.GameMode == "ELIMINATION" {
+				player["lives"] = playerConn.Lives
+				player["eliminated"] = playerConn.Eliminated
+			}
+			players = append(players, player)
 		}
 	}
 	return players
@@ -120,6 +320,34 @@ func calculateScore(answers int, totalquestions int, submissiontime int, totalti
 	return answers * totalquestions * (submissiontime / totaltime)
 }
 
+// latest returns the later of two times.
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// closeRoomConnections closes the sockets of every player and spectator in the room.
+func closeRoomConnections(room *game.Room) {
+	mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(room.Players)+len(room.Spectators))
+	for conn := range room.Players {
+		conns = append(conns, conn)
+	}
+	for conn := range room.Spectators {
+		conns = append(conns, conn)
+	}
+	mu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	if err := room.Audit.Close(); err != nil {
+		slog.Warn("Failed to close audit log", "room", room.Code, "error", err)
+	}
+}
+
 func allPlayersCompleted(room *game.Room) bool {
 	for _, player := range room.Players {
 		if !player.Completed {
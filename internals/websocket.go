@@ -1,12 +1,15 @@
 package internals
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/adimail/fun-with-flags/internals/achievements"
 	"github.com/adimail/fun-with-flags/internals/game"
 	"github.com/gorilla/websocket"
 )
@@ -17,20 +20,77 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// writeWait is how long a write to a WebSocket may take before the client
+// is given up on.
+const writeWait = 10 * time.Second
+
+// maxMessageSize caps the messages read from a client, in bytes. Answers
+// and the other events are a few dozen bytes, the connection is closed on
+// anything bigger.
+const maxMessageSize = 4096
+
+// connWriters holds a mutex for each open WebSocket connection, see
+// writeJSON. Connections are added by HandleWebSocket and removed when it
+// returns.
+var connWriters sync.Map // *websocket.Conn -> *sync.Mutex
+
+// errConnClosed is returned when writing to a connection that has closed.
+var errConnClosed = errors.New("connection closed")
+
+// writeJSON sends a message on a WebSocket connection. gorilla/websocket
+// allows a single writer per connection at a time, while a player is
+// written to from their own loop, broadcasts, the round driver, the timer
+// and the jury finale, so every write goes through here and waits for the
+// one in progress.
+func writeJSON(conn *websocket.Conn, message interface{}) error {
+	writer, ok := connWriters.Load(conn)
+	if !ok {
+		return errConnClosed
+	}
+	lock := writer.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(message)
+}
+
 // HandleWebSocket manages WebSocket connections for a multiplayer game room.
 // It handles the initial connection setup, player registration, and ongoing
 // communication between players in a room. The function supports various game
 // events including player joins, answers, score updates, and departures.
 //
 // The function expects an initial message containing:
-//   - Username: The display name of the connecting player
+//   - Username: The display name of the connecting player, replaced by the
+//     account name when the player is logged in (see resolveUsername)
 //   - RoomID: The unique identifier of the game room to join
+//   - Spectator: Optional, joins the room as a spectator (see handleSpectator)
+//   - Team: Optional, the team to join in team rooms (see assignTeam)
 //
 // It enforces a maximum of 9 players per room and manages the following events:
 //   - "leave": Handle explicit player departure
+//   - "join_team": Switch team in the lobby, before the game starts
 //   - "loadgame": Initialize game countdown and start
 //   - "get_new_question": Send a new question to the requesting player
-//   - "validate_answer": Validate a submitted answer and send the response to the player, broadcasting score updates if correct
+//   - "validate_answer": Validate a submitted answer and send the response to the player, broadcasting score updates if correct.
+//     Only the first answer to each question counts, later ones get an error
+//
+// Answers to "MAP" questions are the clicked point, {"lon", "lat"}, rather
+// than an "answer" name; the server finds the country there (see
+// locateAnswer). In rooms with MapRetries a wrong click gets an
+// "answer_hint" event instead of "answer_result" while retries are left,
+// see offerHint.
+//
+// When the game ends every player gets a "game_result" event, see
+// recordGameResults. Badges earned by logged in players are announced to
+// the room with "badge_awarded" events, see awardBadges.
+//
+// Rooms created with JuryFinale reveal a Eurovision-style scoreboard after
+// "all_players_finished" (see runJuryFinale); "clean_room" waits for it to end.
+//
+// In synchronized rooms the server pushes questions itself (see runRounds):
+// "get_new_question" only returns the question of the open round and
+// "validate_answer" is recorded against that round.
 //
 // Parameters:
 //   - w: The HTTP response writer
@@ -38,34 +98,56 @@ var upgrader = websocket.Upgrader{
 //
 // The connection is automatically closed when the function returns.
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r)
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("WebSocket Upgrade error:", err)
+		logger.Warn("WebSocket upgrade error", "error", err)
 		http.Error(w, "Could not open WebSocket connection", http.StatusInternalServerError)
 		return
 	}
 
 	defer conn.Close()
+	conn.SetReadLimit(maxMessageSize)
+
+	connWriters.Store(conn, &sync.Mutex{})
+	defer connWriters.Delete(conn)
+
+	openSockets.Add(1)
+	defer openSockets.Add(-1)
 
 	var initialMessage struct {
-		Username string `json:"username"`
-		RoomID   string `json:"roomID"`
+		Username  string `json:"username"`
+		RoomID    string `json:"roomID"`
+		Spectator bool   `json:"spectator"`
+		Team      string `json:"team"`
 	}
 	if err := conn.ReadJSON(&initialMessage); err != nil {
-		log.Println("Failed to read initial message:", err)
-		conn.WriteJSON(map[string]string{"error": "Invalid initial message"})
+		logger.Warn("Failed to read initial message", "error", err)
+		writeJSON(conn, map[string]string{"error": "Invalid initial message"})
 		return
 	}
 
+	logger = logger.With("room", initialMessage.RoomID)
+	mu.Lock()
 	room, exists := rooms[initialMessage.RoomID]
+	mu.Unlock()
 
 	if !exists {
-		conn.WriteJSON(map[string]string{"error": "Room not found"})
+		writeJSON(conn, map[string]string{"error": "Room not found"})
+		return
+	}
+
+	username, err := resolveUsername(r, initialMessage.Username)
+	if err != nil {
+		writeJSON(conn, map[string]string{"error": err.Error()})
 		return
 	}
+	initialMessage.Username = username
+	account, _ := currentAccount(r)
 
-	if len(room.Players) >= 9 {
-		conn.WriteJSON(map[string]string{"error": "Room is full, only 9 members can join in one room"})
+	if initialMessage.Spectator {
+		handleSpectator(conn, room, initialMessage.Username, logger)
 		return
 	}
 
@@ -75,11 +157,25 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 		Username:  initialMessage.Username,
 		Score:     0,
 		Completed: false,
+		Team:      assignTeam(room, initialMessage.Team),
 		Conn:      conn,
+		Account:   account.Username,
+		Lives:     room.Lives,
 	}
 
 	// Add the player to the room's Players map
-	room.Players[conn] = player
+	mu.Lock()
+	full := len(room.Players) >= 9
+	if !full {
+		room.Players[conn] = player
+	}
+	mu.Unlock()
+	if full {
+		writeJSON(conn, map[string]string{"error": "Room is full, only 9 members can join in one room"})
+		return
+	}
+	logger = logger.With("player", player.ID)
+	logger.Info("Player joined the room", "username", player.Username, "account", player.Account)
 
 	// Notify all players about the new player
 	broadcastToRoom(room, map[string]interface{}{
@@ -88,6 +184,7 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			"username": player.Username,
 			"score":    player.Score,
 			"id":       player.ID,
+			"team":     player.Team,
 		},
 	})
 
@@ -100,17 +197,58 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 		err := conn.ReadJSON(&message)
 		if err != nil {
-			log.Printf("WebSocket connection closed for player %s: %v", player.Username, err)
+			logger.Info("WebSocket connection closed for player", "error", err)
 			break
 		}
+		countMessage(message.Event)
+		auditReceived(room, player.ID, message.Event, message.Data)
 
 		switch message.Event {
 		case "leave":
-			log.Printf("Player %s left the room", player.Username)
+			logger.Info("Player left the room")
 			removePlayerFromRoom(initialMessage.RoomID, room, conn, player)
 			return
 
+		case "join_team":
+			var requested string
+			if dataMap, ok := message.Data.(map[string]interface{}); ok {
+				requested, _ = dataMap["team"].(string)
+			}
+
+			team, ok := findTeam(room, requested)
+			if room.Start || !ok {
+				writeJSON(conn, map[string]string{"error": "Cannot join this team"})
+				continue
+			}
+
+			player.Team = team
+			broadcastToRoom(room, map[string]interface{}{
+				"event": "team_changed",
+				"data": map[string]interface{}{
+					"id":       player.ID,
+					"username": player.Username,
+					"team":     player.Team,
+				},
+			})
+
 		case "loadgame":
+			// Only the first "loadgame" starts the game, and its round driver.
+			// Elimination needs someone to be the last player standing against.
+			mu.Lock()
+			started := room.Start
+			tooFew := room.GameMode == "ELIMINATION" && len(room.Players) < 2
+			if !started && !tooFew {
+				room.Start = true
+			}
+			mu.Unlock()
+			if started {
+				continue
+			}
+			if tooFew {
+				writeJSON(conn, map[string]string{"error": "Elimination games need at least 2 players"})
+				continue
+			}
+
 			for i := 3; i >= 0; i-- {
 				broadcastToRoom(room, map[string]interface{}{
 					"event": "countdown",
@@ -119,71 +257,102 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 				time.Sleep(1 * time.Second)
 			}
 
-			room.Start = true
+			room.StartedAt = time.Now()
+			gamesStarted.Inc()
 
 			broadcastToRoom(room, map[string]interface{}{
 				"event": "gameStarted",
 			})
 
+			if room.Synchronized {
+				go runRounds(room)
+			}
+
 			go func(room *game.Room) {
 				time.Sleep(time.Duration(room.TimeLimit) * time.Minute)
 
+				// Rooms ended early, e.g. by an admin, are already gone, and a
+				// jury finale closes its room itself once the scoreboard is out.
+				// Removing the room here keeps a finale from starting meanwhile.
+				mu.Lock()
+				over := room.FinaleRunning || room.FinaleDone || rooms[room.Code] != room
+				if !over {
+					delete(rooms, room.Code)
+				}
+				mu.Unlock()
+				if over {
+					return
+				}
+
+				recordGameResults(room)
+				mu.Lock()
+				results := finalResults(room)
+				mu.Unlock()
 				broadcastToRoom(room, map[string]interface{}{
 					"event": "time_over",
+					"data":  results,
 				})
 
-				for conn := range room.Players {
-					conn.Close()
-				}
-
-				delete(rooms, room.Code)
+				closeRoomConnections(room)
 			}(room)
 
 		case "get_new_question":
 			// When a client sends this event, it will send the question index for the question
 			// and this is handeled by returning the room.Questions[requetedindex] question
 			//
-			// This event sends the requested question to the client which requested it using conn.WriteJSON
+			// This event sends the requested question to the client which requested it using writeJSON
 			var questionNumber int
 
 			if dataMap, ok := message.Data.(map[string]interface{}); ok {
 				if questionNumberFloat, ok := dataMap["question_number"].(float64); ok {
 					questionNumber = int(questionNumberFloat)
 				} else {
-					log.Println("Invalid question_number type")
-					conn.WriteJSON(map[string]string{"error": "Invalid question number"})
+					logger.Warn("Invalid question_number type")
+					writeJSON(conn, map[string]string{"error": "Invalid question number"})
 					continue
 				}
 			} else {
-				log.Println("Invalid data format for get_new_question")
-				conn.WriteJSON(map[string]string{"error": "Invalid data format"})
+				logger.Warn("Invalid data format for get_new_question")
+				writeJSON(conn, map[string]string{"error": "Invalid data format"})
 				continue
 			}
 
+			if room.Synchronized {
+				mu.Lock()
+				open := room.Round != nil && room.Round.Index == questionNumber
+				mu.Unlock()
+				if !open {
+					writeJSON(conn, map[string]string{"error": "This question is not open yet"})
+					continue
+				}
+			}
+
 			question, err := getQuestion(room, questionNumber)
 			if err != nil {
-				log.Println("Failed to get question:", err)
-				conn.WriteJSON(map[string]string{"error": "Failed to get question"})
+				logger.Error("Failed to get question", "question", questionNumber, "error", err)
+				writeJSON(conn, map[string]string{"error": "Failed to get question"})
 				continue
 			}
 
-			err = conn.WriteJSON(map[string]interface{}{
+			questionMessage := map[string]interface{}{
 				"event": "new_question",
 				"data":  question,
-			})
-			if err != nil {
-				log.Println("Error sending question to player:", err)
+			}
+			auditMessage(room, player.ID, questionMessage)
+			if err := writeJSON(conn, questionMessage); err != nil {
+				logger.Warn("Error sending question to player", "error", err)
+			} else {
+				questionsServed.Inc(question["type"].(string))
 			}
 
 		case "clean_room":
 			// After all players have finished the game, the memory
 			// is cleared and all room and player instances are erased
-			if allPlayersCompleted(room) {
-				for conn := range room.Players {
-					conn.Close()
-				}
-
-				delete(rooms, initialMessage.RoomID)
+			mu.Lock()
+			finaleOver := !room.JuryFinale || room.FinaleDone
+			mu.Unlock()
+			if allPlayersCompleted(room) && finaleOver {
+				closeRoom(room)
 			}
 
 		case "validate_answer":
@@ -193,8 +362,8 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			var rawData map[string]interface{}
 			rawData, ok := message.Data.(map[string]interface{})
 			if !ok {
-				log.Println("Invalid data type for validate_answer")
-				conn.WriteJSON(map[string]string{"error": "Invalid data format"})
+				logger.Warn("Invalid data type for validate_answer")
+				writeJSON(conn, map[string]string{"error": "Invalid data format"})
 				continue
 			}
 
@@ -206,60 +375,125 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 			if questionIndex, ok := rawData["question_index"].(float64); ok {
 				data.QuestionIndex = int(questionIndex)
 			} else {
-				conn.WriteJSON(map[string]string{"error": "Invalid question index"})
+				writeJSON(conn, map[string]string{"error": "Invalid question index"})
+				continue
+			}
+
+			if data.QuestionIndex < 0 || data.QuestionIndex >= len(room.Questions) {
+				writeJSON(conn, map[string]string{"error": "Invalid question index"})
+				continue
+			}
+
+			// Only the first answer to a question is scored, synchronized
+			// rounds check it themselves (see submitRoundAnswer)
+			mu.Lock()
+			answered := hasAnswered(player, data.QuestionIndex)
+			mu.Unlock()
+			if !room.Synchronized && answered {
+				writeJSON(conn, map[string]string{"error": "You have already answered this question"})
 				continue
 			}
 
-			if answer, ok := rawData["answer"].(string); ok {
+			if room.Questions[strconv.Itoa(data.QuestionIndex)].Type == "MAP" {
+				// Map answers are the clicked point, see locateAnswer
+				lon, okLon := rawData["lon"].(float64)
+				lat, okLat := rawData["lat"].(float64)
+				if !okLon || !okLat {
+					writeJSON(conn, map[string]string{"error": "Invalid coordinates"})
+					continue
+				}
+				answer, err := locateAnswer(lon, lat)
+				if err != nil {
+					writeJSON(conn, map[string]string{"error": err.Error()})
+					continue
+				}
+				data.Answer = answer
+
+				// Wrong clicks get a hint while retries are left
+				if offerHint(room, player, conn, data.QuestionIndex, answer, lon, lat) {
+					continue
+				}
+			} else if answer, ok := rawData["answer"].(string); ok {
 				data.Answer = answer
 			} else {
-				conn.WriteJSON(map[string]string{"error": "Invalid answer"})
+				writeJSON(conn, map[string]string{"error": "Invalid answer"})
 				continue
 			}
 
-			if data.QuestionIndex < 0 || data.QuestionIndex >= len(room.Questions) {
-				conn.WriteJSON(map[string]string{"error": "Invalid question index"})
+			if room.Synchronized {
+				if err := submitRoundAnswer(room, player, conn, data.QuestionIndex, data.Answer); err != nil {
+					writeJSON(conn, map[string]string{"error": err.Error()})
+				}
 				continue
 			}
 
 			question := room.Questions[strconv.Itoa(data.QuestionIndex)]
-			isCorrect := question.Answer == data.Answer
+			result := checkAnswer(question, data.Answer, room.Tolerance)
+			isCorrect := result.Accepted
 
 			messageResponse := map[string]interface{}{
 				"event": "answer_result",
-				"data": map[string]interface{}{
-					"correct_answer": question.Answer,
-					"chosen_answer":  data.Answer,
-				},
+				"data":  answerResult(question, data.Answer, result),
 			}
 
-			err := conn.WriteJSON(messageResponse)
+			auditMessage(room, player.ID, messageResponse)
+			err := writeJSON(conn, messageResponse)
 			if err != nil {
-				log.Println("Error sending validation response to player:", err)
+				logger.Warn("Error sending validation response to player", "error", err)
 			}
 
+			mu.Lock()
+			answeredAt := time.Now()
+			elapsed := answeredAt.Sub(latest(room.StartedAt, player.LastAnswerAt))
+			recordAnswer(player, data.QuestionIndex, isCorrect, elapsed)
+			recordAnswerStats(question, isCorrect, elapsed)
+			player.LastAnswerAt = answeredAt
+
+			var score map[string]interface{}
+			streak := 0
 			if isCorrect {
-				player.Score++
-				broadcastToRoom(room, map[string]interface{}{
-					"event": "score",
-					"data": map[string]interface{}{
-						"username": player.Username,
-						"score":    player.Score,
-					},
+				player.Score += answerPoints(room, player, data.QuestionIndex)
+				score = scoreEvent(room, player)
+				streak = answerStreak(player, data.QuestionIndex)
+			}
+			finished := data.QuestionIndex+1 == len(room.Questions)
+			if finished {
+				player.Completed = true
+			}
+			mu.Unlock()
+
+			if isCorrect {
+				broadcastToRoom(room, score)
+				awardBadges(room, player, achievements.Event{
+					Type:   achievements.AnswerEvent,
+					Streak: streak,
 				})
 			}
 
-			if data.QuestionIndex+1 == len(room.Questions) {
-				player.Completed = true
+			if finished {
 				broadcastToRoom(room, map[string]interface{}{
 					"event":    "finished_game",
 					"username": player.Username,
 				})
 
-				if allPlayersCompleted(room) {
+				mu.Lock()
+				completed := allPlayersCompleted(room)
+				mu.Unlock()
+				if completed {
+					if !room.JuryFinale {
+						recordGameResults(room)
+					}
+					mu.Lock()
+					results := finalResults(room)
+					mu.Unlock()
 					broadcastToRoom(room, map[string]interface{}{
 						"event": "all_players_finished",
+						"data":  results,
 					})
+
+					if room.JuryFinale {
+						go runJuryFinale(room)
+					}
 				}
 
 			}
@@ -286,8 +520,11 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 //   - Cleans up empty rooms
 //   - Handles thread-safe access to shared resources
 func removePlayerFromRoom(roomID string, room *game.Room, conn *websocket.Conn, player *game.Player) {
+	mu.Lock()
 	delete(room.Players, conn)
+	delete(room.Spectators, conn) // eliminated players watch as spectators
 	remainingPlayers := len(room.Players)
+	mu.Unlock()
 
 	// Notify remaining players
 	broadcastToRoom(room, map[string]interface{}{
@@ -298,13 +535,17 @@ func removePlayerFromRoom(roomID string, room *game.Room, conn *websocket.Conn,
 		},
 	})
 
+	if room.Synchronized {
+		checkRoundComplete(room)
+	}
+
 	if remainingPlayers == 0 {
-		delete(rooms, roomID)
-		log.Printf("Room %s has been closed.", roomID)
+		closeRoom(room)
+		slog.Info("Room has been closed", "room", roomID)
 	}
 }
 
-// broadcastToRoom sends a message to all players in a specified room.
+// broadcastToRoom sends a message to all players and spectators in a specified room.
 // It safely handles concurrent access to the room's player list and manages
 // failed message deliveries by removing disconnected players.
 //
@@ -317,11 +558,39 @@ func removePlayerFromRoom(roomID string, room *game.Room, conn *websocket.Conn,
 //   - Attempts to send the message to each connected player
 //   - Handles failed sends by closing connections and removing players
 func broadcastToRoom(room *game.Room, message interface{}) {
+	start := time.Now()
+	defer func() { broadcastDuration.Observe(time.Since(start).Seconds()) }()
+	auditMessage(room, "", message)
+
+	// Players join, leave and get eliminated from other goroutines
+	mu.Lock()
+	players := make(map[*websocket.Conn]*game.Player, len(room.Players))
 	for conn, player := range room.Players {
-		if err := conn.WriteJSON(message); err != nil {
-			log.Printf("Error broadcasting message to player %s: %v", player.Username, err)
+		players[conn] = player
+	}
+	spectators := make(map[*websocket.Conn]*game.Player, len(room.Spectators))
+	for conn, spectator := range room.Spectators {
+		spectators[conn] = spectator
+	}
+	mu.Unlock()
+
+	for conn, player := range players {
+		if err := writeJSON(conn, message); err != nil {
+			slog.Warn("Error broadcasting message to player", "room", room.Code, "player", player.ID, "error", err)
 			conn.Close()
+			mu.Lock()
 			delete(room.Players, conn)
+			mu.Unlock()
+		}
+	}
+
+	for conn, spectator := range spectators {
+		if err := writeJSON(conn, message); err != nil {
+			slog.Warn("Error broadcasting message to spectator", "room", room.Code, "spectator", spectator.ID, "error", err)
+			conn.Close()
+			mu.Lock()
+			delete(room.Spectators, conn)
+			mu.Unlock()
 		}
 	}
 }
@@ -348,6 +617,7 @@ func sendToPlayer(room *game.Room, playerID string, message interface{}) error {
 	var targetConn *websocket.Conn
 	var targetPlayer *game.Player
 
+	mu.Lock()
 	for conn, player := range room.Players {
 		if player.ID == playerID {
 			targetConn = conn
@@ -355,15 +625,19 @@ func sendToPlayer(room *game.Room, playerID string, message interface{}) error {
 			break
 		}
 	}
+	mu.Unlock()
 
 	if targetConn == nil {
 		return fmt.Errorf("player with ID %s not found in room", playerID)
 	}
 
-	if err := targetConn.WriteJSON(message); err != nil {
-		log.Printf("Error sending message to player %s: %v", targetPlayer.Username, err)
+	auditMessage(room, playerID, message)
+	if err := writeJSON(targetConn, message); err != nil {
+		slog.Warn("Error sending message to player", "room", room.Code, "player", targetPlayer.ID, "error", err)
 		targetConn.Close()
+		mu.Lock()
 		delete(room.Players, targetConn)
+		mu.Unlock()
 		return err
 	}
 
@@ -384,12 +658,17 @@ func sendToPlayer(room *game.Room, playerID string, message interface{}) error {
 //   - The question with the specified number is not found in the room
 //
 // Behavior:
-//   - If the room's GameMode is "mcq", the returned map includes the question's options and flag URL.
-//   - For other game modes, the map contains only the flag URL.
+//   - The returned map always includes the question type.
+//   - Multiple choice questions include their options and flag URL, plus the
+//     prompt for trivia questions (capital, continent, neighbour, Eurovision).
+//   - For "REVERSE" questions, the map contains the country name and the flag URLs to choose from.
+//   - For MAP and TYPED questions, the map contains only the flag URL.
 //
 // Example return values:
-//   - For MCQ mode: {"options": [...], "flag_url": "..."}
-//   - For non-MCQ mode: {"flag_url": "..."}
+//   - For MCQ mode: {"type": "MCQ", "options": [...], "flag_url": "..."}
+//   - For a capital question: {"type": "CAPITAL", "prompt": "...", "options": [...], "flag_url": "..."}
+//   - For REVERSE mode: {"type": "REVERSE", "options": ["/static/svg/AL.svg", ...], "country": "..."}
+//   - For MAP mode: {"type": "MAP", "flag_url": "..."}
 func getQuestion(room *game.Room, questionNumber int) (map[string]interface{}, error) {
 	if room == nil {
 		return nil, fmt.Errorf("room is nil")
@@ -415,10 +694,22 @@ func getQuestion(room *game.Room, questionNumber int) (map[string]interface{}, e
 	}
 
 	data := map[string]interface{}{
+		"type":     question.Type,
 		"flag_url": question.FlagURL,
 	}
 
-	if room.GameMode == "MCQ" {
+	if question.Type == "REVERSE" {
+		data = map[string]interface{}{
+			"type":    question.Type,
+			"country": question.Country,
+		}
+	}
+
+	if question.Prompt != "" {
+		data["prompt"] = question.Prompt
+	}
+
+	if len(question.Options) > 0 {
 		data["options"] = question.Options
 	}
 
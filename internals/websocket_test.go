@@ -0,0 +1,86 @@
+package internals
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adimail/fun-with-flags/internals/game"
+	"github.com/gorilla/websocket"
+)
+
+// TestLoadgameStartsOnce checks that a game is started, and counted in the
+// metrics, once however many "loadgame" messages its players send.
+func TestLoadgameStartsOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(HandleWebSocket))
+	defer server.Close()
+
+	room := &game.Room{
+		Code:       "T047",
+		Hostname:   "alice",
+		Players:    make(map[*websocket.Conn]*game.Player),
+		Spectators: make(map[*websocket.Conn]*game.Player),
+		Questions:  map[string]*game.Question{"0": {Type: "MCQ", Code: "FR"}},
+		TimeLimit:  3,
+		GameMode:   "MCQ",
+	}
+	mu.Lock()
+	rooms[room.Code] = room
+	mu.Unlock()
+	defer closeRoom(room)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	var conns []*websocket.Conn
+	for _, name := range []string{"alice", "bob"} {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.WriteJSON(map[string]interface{}{"username": name, "roomID": room.Code})
+		conns = append(conns, conn)
+	}
+
+	// Everyone is in the room once alice saw both join
+	conns[0].SetReadDeadline(time.Now().Add(5 * time.Second))
+	for joined := 0; joined < 2; {
+		var message map[string]interface{}
+		if err := conns[0].ReadJSON(&message); err != nil {
+			t.Fatal(err)
+		}
+		if message["event"] == "playerJoined" {
+			joined++
+		}
+	}
+
+	before := gamesStarted.Value()
+	for _, conn := range conns {
+		conn.WriteJSON(map[string]interface{}{"event": "loadgame"})
+	}
+
+	for _, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		countdowns := 0
+		for {
+			var message map[string]interface{}
+			if err := conn.ReadJSON(&message); err != nil {
+				t.Fatal(err)
+			}
+			if message["event"] == "countdown" {
+				countdowns++
+			}
+			if message["event"] == "gameStarted" {
+				break
+			}
+		}
+		if countdowns != 4 {
+			t.Errorf("got %d countdown events, want 4", countdowns)
+		}
+	}
+
+	if started := gamesStarted.Value() - before; started != 1 {
+		t.Errorf("games started = %v, want 1", started)
+	}
+}
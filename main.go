@@ -1,23 +1,66 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/adimail/fun-with-flags/internals"
 )
 
+// drainDelayEnv names the environment variable setting how long the server
+// keeps serving after a shutdown signal with /readyz failing, so that load
+// balancers stop sending players first. It takes a duration such as "10s".
+const drainDelayEnv = "DRAIN_DELAY"
+
 func main() {
 	const PORT = 8080
+	internals.SetupLogging()
 	r := internals.Router()
 
 	go internals.StartRoomCleanup(15 * time.Minute)
+	go internals.StartDataSaver(time.Minute)
 
 	address := fmt.Sprintf(":%d", PORT)
-	log.Printf("Server started at %s\n", address)
-	if err := http.ListenAndServe(address, r); err != nil {
-		log.Fatal("ListenAndServe: ", err)
+	server := &http.Server{Addr: address, Handler: r}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Info("Server started", "address", address, "version", internals.Version)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("ListenAndServe failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	// Readiness flips first, then the server drains and stops
+	internals.Drain()
+	drainDelay := 5 * time.Second
+	if value := os.Getenv(drainDelayEnv); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			drainDelay = d
+		} else {
+			slog.Warn("Invalid drain delay", "value", value, "error", err)
+		}
+	}
+	time.Sleep(drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down the server", "error", err)
 	}
+	internals.Shutdown()
+	slog.Info("Server stopped")
 }